@@ -0,0 +1,99 @@
+package filter
+
+import (
+	"io/fs"
+	"slices"
+)
+
+// Prune and Print are actions like find. They always match, and apply to the entry if it implements Actor.
+// Builder doesn't reorder expressions across actions, so that they are evaluated in the order of the expression.
+type (
+	Prune struct{}
+	Print struct {
+		terminator string
+	}
+)
+
+// Actor is implemented by entries which actions apply to.
+type Actor interface {
+	// Prune stops descending into the directory.
+	Prune()
+	// Print prints the path followed by the terminator.
+	Print(terminator string)
+}
+
+var (
+	_ FileExp = (*Prune)(nil)
+	_ FileExp = (*Print)(nil)
+)
+
+func NewPrune() *Prune {
+	return new(Prune)
+}
+
+// NewPrint returns the action to print the path followed by a new line.
+func NewPrint() *Print {
+	return &Print{terminator: "\n"}
+}
+
+// NewPrint0 returns the action to print the path followed by a null character.
+func NewPrint0() *Print {
+	return &Print{terminator: "\x00"}
+}
+
+func (*Prune) Match(_ string, info fs.DirEntry) (bool, error) {
+	if a, ok := info.(Actor); ok {
+		a.Prune()
+	}
+	return true, nil
+}
+
+func (p *Print) Match(_ string, info fs.DirEntry) (bool, error) {
+	if a, ok := info.(Actor); ok {
+		a.Print(p.terminator)
+	}
+	return true, nil
+}
+
+func (*Prune) String() string {
+	return "prune"
+}
+
+func (p *Print) String() string {
+	if p.terminator == "\x00" {
+		return "print0"
+	}
+	return "print"
+}
+
+// HasPrint reports whether f has Print.
+// Like find, if the expression has no Print, entries which match the whole expression are printed.
+func HasPrint(f FileExp) bool {
+	return hasAction(f, func(f FileExp) bool {
+		_, ok := f.(*Print)
+		return ok
+	})
+}
+
+// isAction reports whether f is or has an action.
+func isAction(f FileExp) bool {
+	return hasAction(f, func(f FileExp) bool {
+		switch f.(type) {
+		case *Prune, *Print:
+			return true
+		}
+		return false
+	})
+}
+
+func hasAction(f FileExp, is func(FileExp) bool) bool {
+	switch f := f.(type) {
+	case OrExp:
+		return slices.ContainsFunc(f, func(e FileExp) bool { return hasAction(e, is) })
+	case AndExp:
+		return slices.ContainsFunc(f, func(e FileExp) bool { return hasAction(e, is) })
+	case *NotExp:
+		return hasAction(f.filter, is)
+	}
+	return is(f)
+}
@@ -0,0 +1,49 @@
+package filter_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/komem3/fing/filter"
+)
+
+type mockActor struct {
+	mockDirFileInfo
+	pruned bool
+	prints []string
+}
+
+func (m *mockActor) Prune() { m.pruned = true }
+
+func (m *mockActor) Print(terminator string) { m.prints = append(m.prints, terminator) }
+
+func TestAction_Match(t *testing.T) {
+	exp, err := filter.NewBuilder().Name("a").Prune().Print().Or().Name("b").Print0().Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		name   string
+		pruned bool
+		prints []string
+	}{
+		{"a", true, []string{"\n"}},
+		{"b", false, []string{"\x00"}},
+		{"c", false, nil},
+	} {
+		actor := &mockActor{mockDirFileInfo: mockDirFileInfo{name: tt.name}}
+		if _, err := exp.Match(tt.name, actor); err != nil {
+			t.Fatal(err)
+		}
+		if actor.pruned != tt.pruned || !reflect.DeepEqual(actor.prints, tt.prints) {
+			t.Errorf("%s: pruned=%t prints=%q, want pruned=%t prints=%q", tt.name, actor.pruned, actor.prints, tt.pruned, tt.prints)
+		}
+	}
+
+	if !filter.HasPrint(exp) {
+		t.Error("HasPrint want true")
+	}
+	if exp, _ := filter.NewBuilder().Not().Prune().Build(); filter.HasPrint(exp) {
+		t.Error("HasPrint of prune want false")
+	}
+}
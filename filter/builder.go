@@ -0,0 +1,245 @@
+package filter
+
+import (
+	"cmp"
+	"slices"
+)
+
+// Builder builds an expression in the same way as the command line expression.
+// Added expressions are joined by and, and Or starts a new group.
+// The first error of the constructors is returned by Build.
+type Builder struct {
+	or  OrExp
+	and AndExp
+	not bool
+	err error
+}
+
+func NewBuilder() *Builder {
+	return &Builder{}
+}
+
+// Add appends f to the current group. If Not was called before, f is negated.
+func (b *Builder) Add(f FileExp) *Builder {
+	if b.not {
+		b.not = false
+		f = NewNotExp(f)
+	}
+	b.and = append(b.and, f)
+	return b
+}
+
+// Not negates the next expression.
+func (b *Builder) Not() *Builder {
+	b.not = true
+	return b
+}
+
+// Or closes the current group and starts a new one.
+func (b *Builder) Or() *Builder {
+	if len(b.and) > 0 {
+		b.or = append(b.or, b.and)
+		b.and = AndExp{}
+	}
+	return b
+}
+
+// Group returns the current group.
+func (b *Builder) Group() AndExp {
+	return b.and
+}
+
+// Build returns the expression.
+// Expressions in each group are reordered so that costly expressions such as reading content are evaluated last,
+// but not across actions such as Prune.
+// Successive groups which have only a name, like "-name a -o -name b", are folded into a NameSet.
+func (b *Builder) Build() (OrExp, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	exp := make(OrExp, 0, len(b.or)+1)
+	var (
+		names []string
+		fold  bool
+		start int
+	)
+	flush := func() error {
+		if len(names) > 1 {
+			set, err := newNameSet(names, fold)
+			if err != nil {
+				return err
+			}
+			exp = append(exp[:start], AndExp{set})
+		}
+		names = nil
+		return nil
+	}
+	for _, group := range append(slices.Clone(b.or), b.and) {
+		group := sortByCost(group.(AndExp))
+		pattern, ifold, ok := namePattern(group)
+		if !ok || (len(names) > 0 && ifold != fold) {
+			if err := flush(); err != nil {
+				return nil, err
+			}
+		}
+		if ok {
+			if len(names) == 0 {
+				start, fold = len(exp), ifold
+			}
+			names = append(names, pattern)
+		}
+		exp = append(exp, group)
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	return exp, nil
+}
+
+// sortByCost returns a copy of group sorted by cost.
+// Expressions are not moved across actions, which must be evaluated in order.
+func sortByCost(group AndExp) AndExp {
+	group = slices.Clone(group)
+	start := 0
+	for i := 0; i <= len(group); i++ {
+		if i < len(group) && !isAction(group[i]) {
+			continue
+		}
+		slices.SortStableFunc(group[start:i], func(a, b FileExp) int { return cmp.Compare(costOf(a), costOf(b)) })
+		start = i + 1
+	}
+	return group
+}
+
+// namePattern returns the pattern if the group has only FileName or IFileName.
+func namePattern(group AndExp) (pattern string, fold bool, ok bool) {
+	if len(group) != 1 {
+		return "", false, false
+	}
+	switch f := group[0].(type) {
+	case *FileName:
+		return f.pattern, false, true
+	case *IFileName:
+		return f.pattern, true, true
+	}
+	return "", false, false
+}
+
+func (b *Builder) add(f FileExp, err error) *Builder {
+	if err != nil {
+		if b.err == nil {
+			b.err = err
+		}
+		return b
+	}
+	return b.Add(f)
+}
+
+func (b *Builder) Name(pattern string) *Builder {
+	f, err := NewFileName(pattern)
+	return b.add(f, err)
+}
+
+func (b *Builder) IName(pattern string) *Builder {
+	f, err := NewIFileName(pattern)
+	return b.add(f, err)
+}
+
+func (b *Builder) Names(patterns ...string) *Builder {
+	f, err := NewNameSet(patterns...)
+	return b.add(f, err)
+}
+
+func (b *Builder) NameFrom(path string) *Builder {
+	f, err := NewNameSetFromFile(path)
+	return b.add(f, err)
+}
+
+func (b *Builder) Path(pattern string) *Builder {
+	f, err := NewPath(pattern)
+	return b.add(f, err)
+}
+
+func (b *Builder) IPath(pattern string) *Builder {
+	f, err := NewIPath(pattern)
+	return b.add(f, err)
+}
+
+func (b *Builder) Regex(pattern string) *Builder {
+	f, err := NewRegex(pattern)
+	return b.add(f, err)
+}
+
+func (b *Builder) IRegex(pattern string) *Builder {
+	f, err := NewIRegex(pattern)
+	return b.add(f, err)
+}
+
+func (b *Builder) RegexName(pattern string) *Builder {
+	f, err := NewRegexName(pattern)
+	return b.add(f, err)
+}
+
+func (b *Builder) IRegexName(pattern string) *Builder {
+	f, err := NewIRegexName(pattern)
+	return b.add(f, err)
+}
+
+func (b *Builder) Size(size string) *Builder {
+	f, err := NewSize(size)
+	return b.add(f, err)
+}
+
+func (b *Builder) Type(typ string) *Builder {
+	f, err := NewFileType(typ)
+	return b.add(f, err)
+}
+
+func (b *Builder) Executable() *Builder {
+	return b.Add(NewExecutable())
+}
+
+func (b *Builder) Contains(literal string) *Builder {
+	return b.Add(NewContains(literal))
+}
+
+func (b *Builder) Grep(pattern string) *Builder {
+	f, err := NewGrep(pattern)
+	return b.add(f, err)
+}
+
+func (b *Builder) Mime(pattern string) *Builder {
+	f, err := NewMime(pattern)
+	return b.add(f, err)
+}
+
+func (b *Builder) Kind(kind string) *Builder {
+	f, err := NewKind(kind)
+	return b.add(f, err)
+}
+
+func (b *Builder) SHA256(sum string) *Builder {
+	f, err := NewSHA256(sum)
+	return b.add(f, err)
+}
+
+func (b *Builder) MD5(sum string) *Builder {
+	f, err := NewMD5(sum)
+	return b.add(f, err)
+}
+
+func (b *Builder) Bool(v bool) *Builder {
+	return b.Add(AlwasyExp(v))
+}
+
+func (b *Builder) Prune() *Builder {
+	return b.Add(NewPrune())
+}
+
+func (b *Builder) Print() *Builder {
+	return b.Add(NewPrint())
+}
+
+func (b *Builder) Print0() *Builder {
+	return b.Add(NewPrint0())
+}
@@ -0,0 +1,74 @@
+package filter_test
+
+import (
+	"testing"
+
+	"github.com/komem3/fing/filter"
+)
+
+func TestBuilder_Build(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		builder *filter.Builder
+		str     string
+		isErr   bool
+	}{
+		{
+			"and expression",
+			filter.NewBuilder().Name("*.go").Type("f"),
+			"name(*.go) && type(file)",
+			false,
+		},
+		{
+			"or expression",
+			filter.NewBuilder().Name("*.go").Or().Not().IName("*.md"),
+			"name(*.go) || not iname(*.MD)",
+			false,
+		},
+		{
+			"skip empty group",
+			filter.NewBuilder().Or().Bool(true).Or(),
+			"true || ",
+			false,
+		},
+		{
+			"fold names",
+			filter.NewBuilder().Name("*.go").Or().Name("*.md").Or().IName("*.JPG").Or().IName("*.png").Or().Name("x").Type("f").Or().Name("y"),
+			"names(*.go,*.md) || inames(*.JPG,*.png) || name(x) && type(file) || name(y)",
+			false,
+		},
+		{
+			"not fold negated names",
+			filter.NewBuilder().Not().Name("*.go").Or().Name("*.md"),
+			"not name(*.go) || name(*.md)",
+			false,
+		},
+		{
+			"not reorder across actions",
+			filter.NewBuilder().Contains("x").Name("a").Prune().Contains("y").Type("f").Print0(),
+			"name(a) && contains(x) && prune && type(file) && contains(y) && print0",
+			false,
+		},
+		{
+			"invalid argument",
+			filter.NewBuilder().Name("*.go").Type("x").Regex("("),
+			"",
+			true,
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			exp, err := tt.builder.Build()
+			if got := err != nil; got != tt.isErr {
+				t.Fatalf("err != nil want %t, but got %v", tt.isErr, err)
+			}
+			if err != nil {
+				return
+			}
+			if exp.String() != tt.str {
+				t.Errorf("Build want %s, but got %s", tt.str, exp)
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package filter
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"io/fs"
+	"strings"
+)
+
+// Checksum returns the hex encoded hash of the content.
+// If limit is positive, only the first limit bytes are hashed.
+func Checksum(path string, info fs.DirEntry, h hash.Hash, limit int64) (string, error) {
+	f, err := OpenFile(path, info)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if limit > 0 {
+		r = io.LimitReader(f, limit)
+	}
+	if _, err := io.Copy(h, r); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// HashFunc returns the constructor of the hash algorithm. Support sha256 and md5.
+func HashFunc(algo string) (func() hash.Hash, error) {
+	switch algo {
+	case "sha256":
+		return sha256.New, nil
+	case "md5":
+		return md5.New, nil
+	}
+	return nil, fmt.Errorf("%s is unsupported hash algorithm. Support sha256 and md5", algo)
+}
+
+// Hash matches regular files whose hash is the sum.
+type Hash struct {
+	algo    string
+	sum     string
+	newHash func() hash.Hash
+}
+
+var _ FileExp = (*Hash)(nil)
+
+func NewSHA256(sum string) (*Hash, error) {
+	return newHash("sha256", sum, sha256.Size)
+}
+
+func NewMD5(sum string) (*Hash, error) {
+	return newHash("md5", sum, md5.Size)
+}
+
+func newHash(algo, sum string, size int) (*Hash, error) {
+	sum = strings.ToLower(sum)
+	if b, err := hex.DecodeString(sum); err != nil || len(b) != size {
+		return nil, fmt.Errorf("%s is invalid %s. It should be %d hex digits", sum, algo, size*2)
+	}
+	newHash, err := HashFunc(algo)
+	if err != nil {
+		return nil, err
+	}
+	return &Hash{algo: algo, sum: sum, newHash: newHash}, nil
+}
+
+func (h *Hash) Match(path string, info fs.DirEntry) (bool, error) {
+	if !info.Type().IsRegular() {
+		return false, nil
+	}
+	sum, err := Checksum(path, info, h.newHash(), 0)
+	if err != nil {
+		return false, err
+	}
+	return sum == h.sum, nil
+}
+
+func (*Hash) cost() int { return contentCost }
+
+func (h *Hash) String() string {
+	return fmt.Sprintf("%s(%s)", h.algo, h.sum)
+}
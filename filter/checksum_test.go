@@ -0,0 +1,72 @@
+package filter_test
+
+import (
+	"testing"
+
+	"github.com/komem3/fing/filter"
+)
+
+func TestHash_Match(t *testing.T) {
+	t.Parallel()
+	for _, tt := range []struct {
+		name  string
+		exp   func() (*filter.Hash, error)
+		info  *mockContentFile
+		match bool
+	}{
+		{
+			"sha256",
+			func() (*filter.Hash, error) {
+				return filter.NewSHA256("B94D27B9934D3E08A52E52D7DA7DABFAC484EFE37A5380EE9088F7ACE2EFCDE9")
+			},
+			&mockContentFile{data: "hello world"},
+			true,
+		},
+		{
+			"md5",
+			func() (*filter.Hash, error) { return filter.NewMD5("5eb63bbbe01eeed093cb22bb8f5acdc3") },
+			&mockContentFile{data: "hello world"},
+			true,
+		},
+		{
+			"different",
+			func() (*filter.Hash, error) { return filter.NewMD5("5eb63bbbe01eeed093cb22bb8f5acdc3") },
+			&mockContentFile{data: "hello world\n"},
+			false,
+		},
+		{
+			"directory",
+			func() (*filter.Hash, error) { return filter.NewMD5("d41d8cd98f00b204e9800998ecf8427e") },
+			directory,
+			false,
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			f, err := tt.exp()
+			if err != nil {
+				t.Fatal(err)
+			}
+			match, err := f.Match("", tt.info)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if match != tt.match {
+				t.Errorf("match want %t, but got %t", tt.match, match)
+			}
+		})
+	}
+}
+
+func TestNewSHA256_invalid(t *testing.T) {
+	t.Parallel()
+	for _, sum := range []string{"", "xyz", "5eb63bbbe01eeed093cb22bb8f5acdc3"} {
+		if _, err := filter.NewSHA256(sum); err == nil {
+			t.Errorf("%s want error", sum)
+		}
+	}
+	if _, err := filter.HashFunc("sha1"); err == nil {
+		t.Errorf("sha1 want error")
+	}
+}
@@ -0,0 +1,238 @@
+package filter
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CommandExp is implemented by expressions which run commands.
+type CommandExp interface {
+	FileExp
+	// SetContext sets the context, which kills the commands when it is done, and the writer of their stderr.
+	SetContext(ctx context.Context, stderr io.Writer)
+}
+
+// Command matches files for which the command exits with 0.
+// "{}" in the arguments is replaced by the path, and the path is appended if there is no "{}".
+type Command struct {
+	args   []string
+	ctx    context.Context
+	stderr io.Writer
+}
+
+// Coprocess matches files answered by a helper process, which is started once.
+// Paths are written to the stdin of the helper line by line,
+// and the helper answers a line such as yes or no for each path.
+type Coprocess struct {
+	args []string
+
+	mu     sync.Mutex
+	ctx    context.Context
+	stderr io.Writer
+	cmd    *exec.Cmd
+	in     io.WriteCloser
+	out    *bufio.Reader
+	err    error
+	done   bool
+	// kill kills the helper, and stopKill stops killing it when ctx is done.
+	kill     context.CancelFunc
+	stopKill func() bool
+}
+
+var (
+	_ CommandExp = (*Command)(nil)
+	_ CommandExp = (*Coprocess)(nil)
+	_ io.Closer  = (*Coprocess)(nil)
+)
+
+const commandCost = 20
+
+// closeTimeout is the time to wait for the helper to exit after its stdin is closed.
+const closeTimeout = 5 * time.Second
+
+const pathPlaceholder = "{}"
+
+func lookCommand(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("missing command")
+	}
+	_, err := exec.LookPath(args[0])
+	return err
+}
+
+func NewCommand(args []string) (*Command, error) {
+	if err := lookCommand(args); err != nil {
+		return nil, err
+	}
+	return &Command{args: args, ctx: context.Background(), stderr: os.Stderr}, nil
+}
+
+func (c *Command) SetContext(ctx context.Context, stderr io.Writer) {
+	c.ctx, c.stderr = ctx, stderr
+}
+
+func (c *Command) Match(path string, _ fs.DirEntry) (bool, error) {
+	args := make([]string, 0, len(c.args)+1)
+	replaced := false
+	for _, arg := range c.args[1:] {
+		if strings.Contains(arg, pathPlaceholder) {
+			arg, replaced = strings.ReplaceAll(arg, pathPlaceholder, path), true
+		}
+		args = append(args, arg)
+	}
+	if !replaced {
+		args = append(args, path)
+	}
+
+	cmd := exec.CommandContext(c.ctx, c.args[0], args...)
+	cmd.Stderr = c.stderr
+	err := cmd.Run()
+	if c.ctx.Err() != nil {
+		return false, c.ctx.Err()
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (*Command) cost() int { return commandCost }
+
+func (c *Command) String() string {
+	return fmt.Sprintf("filter-cmd(%s)", strings.Join(c.args, " "))
+}
+
+func NewCoprocess(args []string) (*Coprocess, error) {
+	if err := lookCommand(args); err != nil {
+		return nil, err
+	}
+	return &Coprocess{args: args, ctx: context.Background(), stderr: os.Stderr}, nil
+}
+
+// SetContext sets the context. The helper started already is killed when the new context is done.
+func (c *Coprocess) SetContext(ctx context.Context, stderr io.Writer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ctx, c.stderr = ctx, stderr
+	if c.kill != nil {
+		c.stopKill()
+		c.stopKill = context.AfterFunc(ctx, c.kill)
+	}
+}
+
+// start starts the helper at the first match.
+// The helper lives across walks, so it is killed by stopKill instead of the context of exec.
+func (c *Coprocess) start() error {
+	if c.cmd != nil || c.err != nil {
+		return c.err
+	}
+	if err := c.ctx.Err(); err != nil {
+		return err
+	}
+	procCtx, kill := context.WithCancel(context.Background())
+	cmd := exec.CommandContext(procCtx, c.args[0], c.args[1:]...)
+	cmd.Stderr = c.stderr
+	// don't wait for the pipes held by children of the killed helper.
+	cmd.WaitDelay = time.Second
+	in, err := cmd.StdinPipe()
+	if err != nil {
+		kill()
+		c.err = err
+		return err
+	}
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		kill()
+		c.err = err
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		kill()
+		c.err = err
+		return err
+	}
+	c.cmd, c.in, c.out = cmd, in, bufio.NewReader(out)
+	c.kill, c.stopKill = kill, context.AfterFunc(c.ctx, kill)
+	return nil
+}
+
+func (c *Coprocess) Match(path string, _ fs.DirEntry) (bool, error) {
+	if strings.ContainsAny(path, "\r\n") {
+		return false, fmt.Errorf("%s: a path containing a newline can't be sent to %s", path, c.args[0])
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.done {
+		return false, fmt.Errorf("%s is closed", c.args[0])
+	}
+	if err := c.start(); err != nil {
+		return false, err
+	}
+	if c.err != nil {
+		return false, c.err
+	}
+	if _, err := io.WriteString(c.in, path+"\n"); err != nil {
+		c.err = fmt.Errorf("%s exited: %w", c.args[0], err)
+		return false, c.err
+	}
+	line, err := c.out.ReadString('\n')
+	if err != nil {
+		c.err = fmt.Errorf("%s exited without answering: %w", c.args[0], err)
+		return false, c.err
+	}
+	switch answer := strings.TrimSpace(line); strings.ToLower(answer) {
+	case "yes", "y", "true", "1":
+		return true, nil
+	case "no", "n", "false", "0":
+		return false, nil
+	default:
+		c.err = fmt.Errorf("%s answered %q, which is not yes or no", c.args[0], answer)
+		return false, c.err
+	}
+}
+
+// Close closes the stdin of the helper and waits for it to exit.
+// The helper is killed if it doesn't exit in closeTimeout.
+func (c *Coprocess) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.done || c.cmd == nil {
+		c.done = true
+		return nil
+	}
+	c.done = true
+	c.in.Close()
+	timer := time.AfterFunc(closeTimeout, c.kill)
+	err := c.cmd.Wait()
+	timedOut := !timer.Stop()
+	c.stopKill()
+	c.kill()
+	switch {
+	case timedOut:
+		return fmt.Errorf("%s didn't exit in %s after the input was closed, so it was killed", c.args[0], closeTimeout)
+	case c.ctx.Err() != nil:
+		// killed by the context
+		return nil
+	}
+	return err
+}
+
+func (*Coprocess) cost() int { return commandCost }
+
+func (c *Coprocess) String() string {
+	return fmt.Sprintf("filter-coproc(%s)", strings.Join(c.args, " "))
+}
@@ -0,0 +1,131 @@
+package filter_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os/exec"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/komem3/fing/filter"
+)
+
+func TestCommand_Match(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh is not installed")
+	}
+	for _, tt := range []struct {
+		args  []string
+		path  string
+		match bool
+	}{
+		{[]string{"sh", "-c", `test "$0" = a.go`, "{}"}, "a.go", true},
+		{[]string{"sh", "-c", `test "$0" = a.go`, "{}"}, "b.go", false},
+		{[]string{"sh", "-c", `test "$0" = a.go`}, "a.go", true},
+		{[]string{"sh", "-c", `test "$0" = dir/a.go`, "dir/{}"}, "a.go", true},
+	} {
+		tt := tt
+		t.Run(tt.path, func(t *testing.T) {
+			t.Parallel()
+			cmd, err := filter.NewCommand(tt.args)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if match, err := cmd.Match(tt.path, &mockDirFileInfo{}); err != nil || match != tt.match {
+				t.Errorf("Match want %t, but got %t (%v)", tt.match, match, err)
+			}
+		})
+	}
+
+	if _, err := filter.NewCommand([]string{"fing-no-such-command"}); err == nil {
+		t.Error("NewCommand of unknown command want error")
+	}
+}
+
+func TestCoprocess_Match(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh is not installed")
+	}
+	coproc, err := filter.NewCoprocess([]string{"sh", "-c", `while read p; do case $p in *.go) echo yes;; *.txt) echo N;; *) echo maybe;; esac; done`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		path  string
+		match bool
+	}{
+		{"a.go", true},
+		{"a.txt", false},
+		{"b.go", true},
+	} {
+		if match, err := coproc.Match(tt.path, &mockDirFileInfo{}); err != nil || match != tt.match {
+			t.Errorf("Match(%s) want %t, but got %t (%v)", tt.path, tt.match, match, err)
+		}
+	}
+	if _, err := coproc.Match("a\nb.go", &mockDirFileInfo{}); err == nil {
+		t.Error("Match of a path containing a newline want error")
+	}
+	if _, err := coproc.Match("a.md", &mockDirFileInfo{}); err == nil {
+		t.Error("Match of an unexpected answer want error")
+	}
+	if err := coproc.Close(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCoprocess_SetContext(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh is not installed")
+	}
+	coproc, err := filter.NewCoprocess([]string{"sh", "-c", `read p; echo yes; echo hung >&2; exec sleep 100`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	stderr := new(syncBuffer)
+	coproc.SetContext(ctx, stderr)
+	if match, err := coproc.Match("a.go", &mockDirFileInfo{}); err != nil || !match {
+		t.Fatalf("Match want true, but got %t (%v)", match, err)
+	}
+
+	// the hung helper is killed by the context.
+	cancel()
+	start := time.Now()
+	if err := coproc.Close(); err != nil {
+		t.Error(err)
+	}
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Errorf("Close took %s", elapsed)
+	}
+	if got := stderr.String(); got != "hung\n" {
+		t.Errorf("stderr want %q, but got %q", "hung\n", got)
+	}
+
+	cmd, err := filter.NewCommand([]string{"sh", "-c", "true"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cmd.SetContext(ctx, stderr)
+	if _, err := cmd.Match("a.go", &mockDirFileInfo{}); !errors.Is(err, context.Canceled) {
+		t.Errorf("Match with a canceled context want %v, but got %v", context.Canceled, err)
+	}
+}
+
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
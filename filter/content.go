@@ -0,0 +1,170 @@
+package filter
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"regexp"
+)
+
+// Opener is implemented by entries which open their content by themselves,
+// such as entries of an archive. Other entries are opened by the path.
+type Opener interface {
+	Open() (io.ReadCloser, error)
+}
+
+// OpenFile opens the content of the entry.
+func OpenFile(path string, info fs.DirEntry) (io.ReadCloser, error) {
+	if o, ok := info.(Opener); ok {
+		return o.Open()
+	}
+	return os.Open(path)
+}
+
+// costly is implemented by expressions which are expensive to evaluate.
+// Builder evaluates them after cheaper expressions of the same group.
+type costly interface {
+	cost() int
+}
+
+const (
+	noCost      = 0
+	contentCost = 10
+)
+
+func costOf(f FileExp) int {
+	if c, ok := f.(costly); ok {
+		return c.cost()
+	}
+	return noCost
+}
+
+// ReadsFile reports whether f reads the file or the repository, not only the name and the metadata of entries.
+func ReadsFile(f FileExp) bool {
+	return costOf(f) >= contentCost
+}
+
+// binaryCheckSize is the size to look for a null byte, which is the same as git.
+const binaryCheckSize = 8000
+
+type Content struct {
+	literal []byte
+	reg     *regexp.Regexp
+	limit   int64
+}
+
+// Line is a line matched by Content.
+type Line struct {
+	Num  int
+	Text string
+}
+
+var _ FileExp = (*Content)(nil)
+
+func NewContains(literal string) *Content {
+	return &Content{literal: []byte(literal)}
+}
+
+func NewGrep(pattern string) (*Content, error) {
+	reg, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	return &Content{reg: reg}, nil
+}
+
+// SetLimit limits the bytes to read from each file. Zero means no limit.
+func (c *Content) SetLimit(limit int64) {
+	c.limit = limit
+}
+
+func (c *Content) Match(path string, info fs.DirEntry) (bool, error) {
+	var match bool
+	err := c.scan(path, info, func(Line) bool {
+		match = true
+		return false
+	})
+	return match, err
+}
+
+// Lines returns the lines which match. Binary files have no line.
+func (c *Content) Lines(path string, info fs.DirEntry) ([]Line, error) {
+	var lines []Line
+	err := c.scan(path, info, func(l Line) bool {
+		lines = append(lines, l)
+		return true
+	})
+	return lines, err
+}
+
+func (c *Content) scan(path string, info fs.DirEntry, fn func(Line) bool) error {
+	if !info.Type().IsRegular() {
+		return nil
+	}
+	f, err := OpenFile(path, info)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if c.limit > 0 {
+		r = io.LimitReader(f, c.limit)
+	}
+	reader := bufio.NewReaderSize(r, 64*1024)
+	head, err := reader.Peek(binaryCheckSize)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, bufio.ErrBufferFull) {
+		return err
+	}
+	if bytes.IndexByte(head, 0) >= 0 {
+		return nil
+	}
+
+	var long []byte
+	for num := 1; ; {
+		line, err := reader.ReadSlice('\n')
+		if errors.Is(err, bufio.ErrBufferFull) {
+			long = append(long, line...)
+			continue
+		}
+		if long != nil {
+			line = append(long, line...)
+			long = nil
+		}
+		if len(line) > 0 {
+			line = bytes.TrimSuffix(bytes.TrimSuffix(line, []byte("\n")), []byte("\r"))
+			if c.matchLine(line) && !fn(Line{Num: num, Text: string(line)}) {
+				return nil
+			}
+			num++
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (c *Content) matchLine(line []byte) bool {
+	if c.reg != nil {
+		return c.reg.Match(line)
+	}
+	return bytes.Contains(line, c.literal)
+}
+
+func (*Content) cost() int {
+	return contentCost
+}
+
+func (c *Content) String() string {
+	if c.reg != nil {
+		return fmt.Sprintf("grep(%s)", c.reg)
+	}
+	return fmt.Sprintf("contains(%s)", c.literal)
+}
@@ -0,0 +1,86 @@
+package filter_test
+
+import (
+	"io"
+	"io/fs"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/komem3/fing/filter"
+)
+
+type mockContentFile struct {
+	mockDirFileInfo
+	data string
+}
+
+var _ filter.Opener = (*mockContentFile)(nil)
+
+func (m *mockContentFile) Open() (io.ReadCloser, error) {
+	return io.NopCloser(strings.NewReader(m.data)), nil
+}
+
+var contentFile = &mockContentFile{data: "package main\r\n\nfunc main() {\n\tprintln(\"hello\")\n}\n"}
+
+func TestContent_Match(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		filter *filter.Content
+		info   fs.DirEntry
+		match  bool
+	}{
+		{"contains", filter.NewContains("println"), contentFile, true},
+		{"not contains", filter.NewContains("fmt"), contentFile, false},
+		{"grep", mustContent(filter.NewGrep(`^func \w+\(`)), contentFile, true},
+		{"grep line end", mustContent(filter.NewGrep(`main$`)), contentFile, true},
+		{"binary", filter.NewContains("main"), &mockContentFile{data: "main\x00"}, false},
+		{"directory", filter.NewContains(""), &mockContentFile{mockDirFileInfo: mockDirFileInfo{isDir: true, typ: fs.ModeDir}}, false},
+		{"over limit", limitContent(filter.NewContains("println"), 20), contentFile, false},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			match, err := tt.filter.Match("", tt.info)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if match != tt.match {
+				t.Errorf("match want %t, but got %t", tt.match, match)
+			}
+		})
+	}
+}
+
+func TestContent_Lines(t *testing.T) {
+	lines, err := mustContent(filter.NewGrep("main")).Lines("", contentFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []filter.Line{{Num: 1, Text: "package main"}, {Num: 3, Text: "func main() {"}}
+	if !reflect.DeepEqual(want, lines) {
+		t.Errorf("Lines want %v, but got %v", want, lines)
+	}
+}
+
+func TestBuilder_Build_cost(t *testing.T) {
+	exp, err := filter.NewBuilder().Contains("main").Not().Grep("x").Name("*.go").Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "name(*.go) && contains(main) && not grep(x)"; exp.String() != want {
+		t.Errorf("Build want %s, but got %s", want, exp)
+	}
+}
+
+func mustContent(c *filter.Content, err error) *filter.Content {
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+func limitContent(c *filter.Content, limit int64) *filter.Content {
+	c.SetLimit(limit)
+	return c
+}
@@ -0,0 +1,64 @@
+package filter
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+)
+
+// Empty matches empty regular files and directories which have no entries, like find's -empty.
+type Empty struct{}
+
+// DirReader is implemented by entries which can read their directory.
+// Empty uses it to share the read with the walk.
+type DirReader interface {
+	ReadDir() ([]fs.DirEntry, error)
+}
+
+var _ FileExp = (*Empty)(nil)
+
+// readDirCost is lower than reading content, but higher than the metadata.
+const readDirCost = 5
+
+func NewEmpty() *Empty {
+	return new(Empty)
+}
+
+func (*Empty) Match(path string, info fs.DirEntry) (bool, error) {
+	switch {
+	case info.Type().IsRegular():
+		inf, err := info.Info()
+		if err != nil {
+			return false, err
+		}
+		return inf.Size() == 0, nil
+	case info.IsDir():
+		if r, ok := info.(DirReader); ok {
+			entries, err := r.ReadDir()
+			if err != nil {
+				return false, err
+			}
+			return len(entries) == 0, nil
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return false, err
+		}
+		defer f.Close()
+		if _, err := f.Readdirnames(1); err != nil {
+			if errors.Is(err, io.EOF) {
+				return true, nil
+			}
+			return false, err
+		}
+		return false, nil
+	}
+	return false, nil
+}
+
+func (*Empty) cost() int { return readDirCost }
+
+func (*Empty) String() string {
+	return "empty"
+}
@@ -0,0 +1,55 @@
+package filter_test
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/komem3/fing/filter"
+)
+
+type mockDirReader struct {
+	mockDirFileInfo
+	entries []fs.DirEntry
+}
+
+func (m *mockDirReader) ReadDir() ([]fs.DirEntry, error) {
+	return m.entries, nil
+}
+
+func TestEmpty_Match(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "empty"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "file"), []byte("x"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		name  string
+		path  string
+		arg   fs.DirEntry
+		match bool
+	}{
+		{"empty file", "", &mockDirFileInfo{}, true},
+		{"file", "", &mockDirFileInfo{size: 1}, false},
+		{"symlink", "", &mockDirFileInfo{typ: fs.ModeSymlink}, false},
+		{"empty dir", filepath.Join(dir, "empty"), &mockDirFileInfo{isDir: true, typ: fs.ModeDir}, true},
+		{"dir", dir, &mockDirFileInfo{isDir: true, typ: fs.ModeDir}, false},
+		{"empty reader", dir, &mockDirReader{mockDirFileInfo: mockDirFileInfo{isDir: true, typ: fs.ModeDir}}, true},
+		{
+			"reader", filepath.Join(dir, "empty"),
+			&mockDirReader{mockDirFileInfo{isDir: true, typ: fs.ModeDir}, []fs.DirEntry{&mockDirFileInfo{}}}, false,
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if match, err := filter.NewEmpty().Match(tt.path, tt.arg); err != nil || match != tt.match {
+				t.Errorf("Match want %t, but got %t (%v)", tt.match, match, err)
+			}
+		})
+	}
+}
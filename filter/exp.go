@@ -67,6 +67,26 @@ func (n *NotExp) Match(path string, info fs.DirEntry) (bool, error) {
 	return !match, nil
 }
 
+func (e OrExp) cost() int {
+	var c int
+	for _, f := range e {
+		c = max(c, costOf(f))
+	}
+	return c
+}
+
+func (e AndExp) cost() int {
+	var c int
+	for _, f := range e {
+		c = max(c, costOf(f))
+	}
+	return c
+}
+
+func (n *NotExp) cost() int {
+	return costOf(n.filter)
+}
+
 func (e OrExp) String() string {
 	var buf strings.Builder
 	for i, f := range e {
@@ -1,8 +1,19 @@
 package filter
 
+import "strings"
+
 func (g *Gitignore) Len() int {
 	if g == nil {
 		return 0
 	}
 	return len(g.PathMatchers)
 }
+
+// MountType returns the type of the path and the type of the device in the mountinfo.
+func MountType(mountinfo, path string, major, minor uint64) (pathType, devType string, err error) {
+	table, err := parseMountInfo(strings.NewReader(mountinfo))
+	if err != nil {
+		return "", "", err
+	}
+	return table.typeOf(path), table.devices[mkdev(major, minor)], nil
+}
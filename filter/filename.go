@@ -9,8 +9,14 @@ import (
 )
 
 type (
-	FileName  struct{ *glob.Glob }
-	IFileName struct{ *glob.Glob }
+	FileName struct {
+		*glob.Glob
+		pattern string
+	}
+	IFileName struct {
+		*glob.Glob
+		pattern string
+	}
 )
 
 var (
@@ -23,7 +29,7 @@ func NewFileName(pattern string) (*FileName, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &FileName{Glob: glob}, nil
+	return &FileName{Glob: glob, pattern: pattern}, nil
 }
 
 func NewIFileName(pattern string) (*IFileName, error) {
@@ -31,7 +37,7 @@ func NewIFileName(pattern string) (*IFileName, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &IFileName{Glob: glob}, nil
+	return &IFileName{Glob: glob, pattern: pattern}, nil
 }
 
 func (f FileName) Match(_ string, info fs.DirEntry) (bool, error) {
@@ -0,0 +1,94 @@
+package filter
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// FileTypes is a table of file types and glob patterns of their file names, like ripgrep's -t option.
+type FileTypes map[string][]string
+
+var defaultFileTypes = FileTypes{
+	"c":         {"*.c", "*.h"},
+	"cmake":     {"CMakeLists.txt", "*.cmake"},
+	"cpp":       {"*.cpp", "*.cc", "*.cxx", "*.c++", "*.hpp", "*.hh", "*.hxx", "*.h", "*.inl"},
+	"csharp":    {"*.cs", "*.csx"},
+	"css":       {"*.css", "*.scss", "*.sass", "*.less"},
+	"docker":    {"Dockerfile", "Dockerfile.*", "*.dockerfile", "Containerfile", ".dockerignore"},
+	"go":        {"*.go"},
+	"gomod":     {"go.mod", "go.sum", "go.work", "go.work.sum"},
+	"html":      {"*.html", "*.htm", "*.xhtml"},
+	"java":      {"*.java", "*.jsp"},
+	"js":        {"*.js", "*.jsx", "*.mjs", "*.cjs", "*.vue"},
+	"json":      {"*.json", "*.jsonl", "*.geojson", "composer.lock"},
+	"kotlin":    {"*.kt", "*.kts"},
+	"lua":       {"*.lua"},
+	"make":      {"Makefile", "makefile", "GNUmakefile", "*.mk", "*.mak"},
+	"markdown":  {"*.md", "*.markdown", "*.mdown", "*.mkd", "*.mdx"},
+	"nix":       {"*.nix"},
+	"php":       {"*.php", "*.php3", "*.php4", "*.php5", "*.phtml"},
+	"proto":     {"*.proto"},
+	"py":        {"*.py", "*.pyi"},
+	"ruby":      {"*.rb", "*.gemspec", "Gemfile", "Rakefile", ".irbrc"},
+	"rust":      {"*.rs"},
+	"sh":        {"*.sh", "*.bash", "*.zsh", ".bashrc", ".bash_profile", ".zshrc", ".profile"},
+	"sql":       {"*.sql", "*.psql"},
+	"swift":     {"*.swift"},
+	"terraform": {"*.tf", "*.tfvars", "*.tf.json"},
+	"toml":      {"*.toml", "Cargo.lock"},
+	"ts":        {"*.ts", "*.tsx", "*.cts", "*.mts"},
+	"txt":       {"*.txt"},
+	"vim":       {"*.vim", ".vimrc", ".gvimrc", "vimrc", "gvimrc"},
+	"xml":       {"*.xml", "*.xsd", "*.xsl", "*.xslt", "*.svg"},
+	"yaml":      {"*.yaml", "*.yml"},
+}
+
+// DefaultFileTypes returns a copy of the built-in file types.
+func DefaultFileTypes() FileTypes {
+	types := make(FileTypes, len(defaultFileTypes))
+	for name, globs := range defaultFileTypes {
+		types[name] = slices.Clone(globs)
+	}
+	return types
+}
+
+// Add adds glob patterns to a file type, which is created if it doesn't exist.
+// The definition is "name:glob", and globs can be separated by commas.
+func (t FileTypes) Add(def string) error {
+	name, globs, ok := strings.Cut(def, ":")
+	if !ok || name == "" || globs == "" {
+		return fmt.Errorf("%s is invalid file type definition. The format is 'name:glob'", def)
+	}
+	for _, g := range strings.Split(globs, ",") {
+		if g == "" {
+			return fmt.Errorf("%s has an empty glob", def)
+		}
+		t[name] = append(t[name], g)
+	}
+	return nil
+}
+
+// Matcher returns a NameSet matching the file type.
+func (t FileTypes) Matcher(name string) (*NameSet, error) {
+	globs, ok := t[name]
+	if !ok {
+		return nil, fmt.Errorf("%s is unknown file type. See -type-list", name)
+	}
+	n, err := NewNameSet(globs...)
+	if err != nil {
+		return nil, err
+	}
+	n.label = fmt.Sprintf("filetype(%s)", name)
+	return n, nil
+}
+
+// Names returns the sorted names of file types.
+func (t FileTypes) Names() []string {
+	names := make([]string, 0, len(t))
+	for name := range t {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
@@ -0,0 +1,141 @@
+package filter
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/fs"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// FSType matches files on a file system of the type.
+type FSType struct {
+	typ    string
+	mounts *mountTable
+}
+
+// mountTable is the file system types of mounts by the device and by the mount point.
+type mountTable struct {
+	devices map[uint64]string
+	points  []mountPoint
+}
+
+type mountPoint struct {
+	path string
+	typ  string
+}
+
+var _ FileExp = (*FSType)(nil)
+
+func NewFSType(typ string) (*FSType, error) {
+	if typ == "" {
+		return nil, fmt.Errorf("missing file system type")
+	}
+	mounts, err := loadMounts()
+	if err != nil {
+		return nil, err
+	}
+	return &FSType{typ: typ, mounts: mounts}, nil
+}
+
+func (f *FSType) Match(path string, entry fs.DirEntry) (bool, error) {
+	info, err := entry.Info()
+	if err != nil {
+		return false, err
+	}
+	if dev, ok := deviceOf(info); ok {
+		if typ, ok := f.mounts.devices[dev]; ok {
+			return typ == f.typ, nil
+		}
+	}
+	// the device of some file systems such as btrfs subvolumes differs from the mount table.
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return false, err
+	}
+	return f.mounts.typeOf(abs) == f.typ, nil
+}
+
+func (f *FSType) String() string {
+	return fmt.Sprintf("fstype(%s)", f.typ)
+}
+
+// typeOf returns the type of the deepest mount point containing the absolute path.
+func (t *mountTable) typeOf(path string) string {
+	var found mountPoint
+	for _, p := range t.points {
+		if len(p.path) < len(found.path) {
+			continue
+		}
+		if p.path == "/" || path == p.path || strings.HasPrefix(path, p.path+"/") {
+			found = p
+		}
+	}
+	return found.typ
+}
+
+// parseMountInfo parses the format of /proc/self/mountinfo.
+// Later mounts hide earlier mounts on the same mount point.
+func parseMountInfo(r io.Reader) (*mountTable, error) {
+	table := &mountTable{devices: make(map[uint64]string)}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		// 36 35 98:0 /mnt1 /mnt/parent rw,noatime master:1 - ext3 /dev/root rw,errors=continue
+		fields := strings.Fields(scanner.Text())
+		sep := -1
+		for i := 6; i < len(fields); i++ {
+			if fields[i] == "-" {
+				sep = i
+				break
+			}
+		}
+		if len(fields) < 5 || sep < 0 || sep+1 >= len(fields) {
+			return nil, fmt.Errorf("invalid mountinfo line: %s", scanner.Text())
+		}
+		major, minor, ok := strings.Cut(fields[2], ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid device in mountinfo: %s", fields[2])
+		}
+		ma, err := strconv.ParseUint(major, 10, 32)
+		if err != nil {
+			return nil, err
+		}
+		mi, err := strconv.ParseUint(minor, 10, 32)
+		if err != nil {
+			return nil, err
+		}
+		typ := fields[sep+1]
+		table.devices[mkdev(ma, mi)] = typ
+		table.points = append(table.points, mountPoint{path: unescapeMount(fields[4]), typ: typ})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return table, nil
+}
+
+// mkdev returns the device number of Linux from the major and minor numbers.
+func mkdev(major, minor uint64) uint64 {
+	return (major&0xfffff000)<<32 | (major&0x00000fff)<<8 | (minor&0xffffff00)<<12 | minor&0x000000ff
+}
+
+// unescapeMount unescapes octal escapes such as \040 for a space.
+func unescapeMount(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+4 <= len(s) {
+			if n, err := strconv.ParseUint(s[i+1:i+4], 8, 8); err == nil {
+				b.WriteByte(byte(n))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
@@ -0,0 +1,23 @@
+package filter
+
+import (
+	"io/fs"
+	"os"
+	"syscall"
+)
+
+func loadMounts() (*mountTable, error) {
+	f, err := os.Open("/proc/self/mountinfo")
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return parseMountInfo(f)
+}
+
+func deviceOf(info fs.FileInfo) (uint64, bool) {
+	if st, ok := info.Sys().(*syscall.Stat_t); ok {
+		return uint64(st.Dev), true
+	}
+	return 0, false
+}
@@ -0,0 +1,17 @@
+//go:build !linux
+
+package filter
+
+import (
+	"errors"
+	"io/fs"
+)
+
+// loadMounts is not supported, because there is no /proc/self/mountinfo on this platform.
+func loadMounts() (*mountTable, error) {
+	return nil, errors.New("-fstype is supported only on Linux")
+}
+
+func deviceOf(fs.FileInfo) (uint64, bool) {
+	return 0, false
+}
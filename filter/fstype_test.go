@@ -0,0 +1,41 @@
+package filter_test
+
+import (
+	"testing"
+
+	"github.com/komem3/fing/filter"
+)
+
+func TestMountType(t *testing.T) {
+	const mountinfo = `22 1 259:2 / / rw,relatime shared:1 - ext4 /dev/nvme0n1p2 rw
+23 22 0:22 / /proc rw,relatime shared:5 - proc proc rw
+24 22 0:40 / /mnt/my\040disk rw,relatime shared:6 - nfs4 server:/export rw
+25 22 0:41 / /tmp rw - tmpfs tmpfs rw
+26 22 0:42 / /tmp rw - overlay overlay rw
+`
+	for _, tt := range []struct {
+		path         string
+		major, minor uint64
+		pathType     string
+		devType      string
+	}{
+		{"/home/user", 259, 2, "ext4", "ext4"},
+		{"/proc/self", 0, 22, "proc", "proc"},
+		{"/procfs", 259, 2, "ext4", "ext4"},
+		{"/mnt/my disk/a.txt", 0, 40, "nfs4", "nfs4"},
+		{"/tmp/a", 0, 42, "overlay", "overlay"},
+		{"/tmp/a", 0, 99, "overlay", ""},
+	} {
+		pathType, devType, err := filter.MountType(mountinfo, tt.path, tt.major, tt.minor)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if pathType != tt.pathType || devType != tt.devType {
+			t.Errorf("%s: MountType want (%s, %s), but got (%s, %s)", tt.path, tt.pathType, tt.devType, pathType, devType)
+		}
+	}
+
+	if _, _, err := filter.MountType("22 1 259:2 / / rw\n", "/", 0, 0); err == nil {
+		t.Error("MountType of invalid mountinfo want error")
+	}
+}
@@ -0,0 +1,210 @@
+package filter
+
+import (
+	"fmt"
+	"io/fs"
+	"unicode"
+)
+
+// Fuzzy matches paths which contain the characters of the query in order, like fzf.
+// The match is case insensitive unless the query has an upper case letter.
+type Fuzzy struct {
+	str           string
+	query         []rune
+	caseSensitive bool
+}
+
+var _ FileExp = (*Fuzzy)(nil)
+
+// Scores are the same as fzf.
+const (
+	scoreMatch        = 16
+	scoreGapStart     = -3
+	scoreGapExtension = -1
+
+	bonusBoundary          = scoreMatch / 2
+	bonusNonWord           = scoreMatch / 2
+	bonusCamel123          = bonusBoundary + scoreGapExtension
+	bonusConsecutive       = -(scoreGapStart + scoreGapExtension)
+	bonusBoundaryWhite     = bonusBoundary + 2
+	bonusBoundaryDelimiter = bonusBoundary + 1
+	bonusFirstCharMultiple = 2
+)
+
+type charClass int
+
+const (
+	charWhite charClass = iota
+	charNonWord
+	charDelimiter
+	charLower
+	charUpper
+	charLetter
+	charNumber
+)
+
+func NewFuzzy(query string) *Fuzzy {
+	f := &Fuzzy{str: query, query: []rune(query)}
+	for _, r := range f.query {
+		if unicode.IsUpper(r) {
+			f.caseSensitive = true
+			break
+		}
+	}
+	if !f.caseSensitive {
+		for i, r := range f.query {
+			f.query[i] = unicode.ToLower(r)
+		}
+	}
+	return f
+}
+
+func (f *Fuzzy) Match(path string, _ fs.DirEntry) (bool, error) {
+	_, ok := f.Score(path)
+	return ok, nil
+}
+
+// Score returns the score of the path, which is higher when the query matches
+// consecutive characters or the beginning of words and path elements.
+// The second value reports whether the path matches.
+func (f *Fuzzy) Score(path string) (int, bool) {
+	if len(f.query) == 0 {
+		return 0, true
+	}
+	text := []rune(path)
+
+	// find the first end of the subsequence.
+	pidx, end := 0, -1
+	for i, r := range text {
+		if f.normalize(r) == f.query[pidx] {
+			pidx++
+			if pidx == len(f.query) {
+				end = i
+				break
+			}
+		}
+	}
+	if end < 0 {
+		return 0, false
+	}
+	// find the last start backward to score the shortest match.
+	start := 0
+	pidx = len(f.query) - 1
+	for i := end; i >= 0; i-- {
+		if f.normalize(text[i]) == f.query[pidx] {
+			pidx--
+			if pidx < 0 {
+				start = i
+				break
+			}
+		}
+	}
+	return f.score(text, start, end), true
+}
+
+func (f *Fuzzy) score(text []rune, start, end int) int {
+	var (
+		score       int
+		pidx        int
+		inGap       bool
+		consecutive int
+		firstBonus  int
+		prevClass   = charWhite
+	)
+	if start > 0 {
+		prevClass = classOf(text[start-1])
+	}
+	for i := start; i <= end; i++ {
+		r := text[i]
+		class := classOf(r)
+		if pidx < len(f.query) && f.normalize(r) == f.query[pidx] {
+			score += scoreMatch
+			bonus := bonusFor(prevClass, class)
+			if consecutive == 0 {
+				firstBonus = bonus
+			} else {
+				if bonus >= bonusBoundary && bonus > firstBonus {
+					firstBonus = bonus
+				}
+				bonus = max(bonus, firstBonus, bonusConsecutive)
+			}
+			if pidx == 0 {
+				score += bonus * bonusFirstCharMultiple
+			} else {
+				score += bonus
+			}
+			inGap = false
+			consecutive++
+			pidx++
+		} else {
+			if inGap {
+				score += scoreGapExtension
+			} else {
+				score += scoreGapStart
+			}
+			inGap = true
+			consecutive = 0
+			firstBonus = 0
+		}
+		prevClass = class
+	}
+	return score
+}
+
+func (f *Fuzzy) normalize(r rune) rune {
+	if f.caseSensitive {
+		return r
+	}
+	return unicode.ToLower(r)
+}
+
+func classOf(r rune) charClass {
+	switch {
+	case r >= 'a' && r <= 'z':
+		return charLower
+	case r >= 'A' && r <= 'Z':
+		return charUpper
+	case r >= '0' && r <= '9':
+		return charNumber
+	case r == '/' || r == '\\' || r == ',' || r == ':' || r == ';' || r == '|':
+		return charDelimiter
+	case unicode.IsSpace(r):
+		return charWhite
+	case unicode.IsLower(r):
+		return charLower
+	case unicode.IsUpper(r):
+		return charUpper
+	case unicode.IsNumber(r):
+		return charNumber
+	case unicode.IsLetter(r):
+		return charLetter
+	}
+	return charNonWord
+}
+
+func bonusFor(prev, class charClass) int {
+	if class > charDelimiter {
+		switch prev {
+		case charWhite:
+			return bonusBoundaryWhite
+		case charDelimiter:
+			return bonusBoundaryDelimiter
+		case charNonWord:
+			return bonusBoundary
+		}
+	}
+	if prev == charLower && class == charUpper || prev != charNumber && class == charNumber {
+		return bonusCamel123
+	}
+	switch class {
+	case charNonWord, charDelimiter:
+		return bonusNonWord
+	case charWhite:
+		return bonusBoundaryWhite
+	}
+	return 0
+}
+
+func (f *Fuzzy) String() string {
+	return fmt.Sprintf("fuzzy(%s)", f.str)
+}
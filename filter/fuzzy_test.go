@@ -0,0 +1,67 @@
+package filter_test
+
+import (
+	"testing"
+
+	"github.com/komem3/fing/filter"
+)
+
+func TestFuzzy_Match(t *testing.T) {
+	t.Parallel()
+	for _, tt := range []struct {
+		query string
+		path  string
+		match bool
+	}{
+		{"wkop", "walk/option.go", true},
+		{"WO", "walk/option.go", false},
+		{"wo", "Walk/Option.go", true},
+		{"WO", "Walk/Option.go", true},
+		{"owk", "walk/option.go", false},
+		{"", "walk/option.go", true},
+	} {
+		tt := tt
+		t.Run(tt.query+" "+tt.path, func(t *testing.T) {
+			t.Parallel()
+			match, err := filter.NewFuzzy(tt.query).Match(tt.path, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if match != tt.match {
+				t.Errorf("match want %t, but got %t", tt.match, match)
+			}
+		})
+	}
+}
+
+func TestFuzzy_Score(t *testing.T) {
+	t.Parallel()
+	for _, tt := range []struct {
+		query  string
+		better string
+		worse  string
+	}{
+		{"opt", "walk/option.go", "walk/loop_test.go"},
+		{"wo", "walk/option.go", "aw/xo.go"},
+		{"fb", "filter/builder.go", "foobar.go"},
+		{"main", "main.go", "cmd/domain.go"},
+		{"wt", "walk/walkTest.go", "walk/walktest.go"},
+	} {
+		tt := tt
+		t.Run(tt.query, func(t *testing.T) {
+			t.Parallel()
+			f := filter.NewFuzzy(tt.query)
+			better, ok := f.Score(tt.better)
+			if !ok {
+				t.Fatalf("%s is not matched", tt.better)
+			}
+			worse, ok := f.Score(tt.worse)
+			if !ok {
+				t.Fatalf("%s is not matched", tt.worse)
+			}
+			if better <= worse {
+				t.Errorf("score of %s(%d) want higher than %s(%d)", tt.better, better, tt.worse, worse)
+			}
+		})
+	}
+}
@@ -22,14 +22,27 @@ type Gitignore struct {
 var _ FileExp = (*Gitignore)(nil)
 
 func NewGitIgnore(rootDir string, filePath string) (*Gitignore, error) {
-	domain := strings.Split(rootDir, separator)
-	if len(domain) > 0 && domain[0] == "." {
-		domain = domain[1:]
-	}
 	buf, err := os.ReadFile(filePath)
 	if err != nil {
 		return nil, err
 	}
+	return parseGitIgnore(strings.Split(rootDir, separator), buf), nil
+}
+
+// NewGitIgnoreFS is like NewGitIgnore, but reads filePath from fsys.
+// rootDir and filePath are slash-separated paths of fsys.
+func NewGitIgnoreFS(fsys fs.FS, rootDir string, filePath string) (*Gitignore, error) {
+	buf, err := fs.ReadFile(fsys, filePath)
+	if err != nil {
+		return nil, err
+	}
+	return parseGitIgnore(strings.Split(rootDir, "/"), buf), nil
+}
+
+func parseGitIgnore(domain []string, buf []byte) *Gitignore {
+	if len(domain) > 0 && domain[0] == "." {
+		domain = domain[1:]
+	}
 	var ignores []gitignore.Pattern
 	reader := bufio.NewReader(bytes.NewReader(buf))
 	for {
@@ -43,7 +56,7 @@ func NewGitIgnore(rootDir string, filePath string) (*Gitignore, error) {
 		}
 		ignores = append(ignores, gitignore.ParsePattern(string(b), domain))
 	}
-	return &Gitignore{PathMatchers: ignores}, nil
+	return &Gitignore{PathMatchers: ignores}
 }
 
 func (g *Gitignore) Match(path string, info fs.DirEntry) (bool, error) {
@@ -1,19 +1,17 @@
 package filter_test
 
 import (
-	"os"
 	"path/filepath"
 	"reflect"
 	"strings"
 	"testing"
+	"testing/fstest"
 
 	"github.com/go-git/go-git/v5/plumbing/format/gitignore"
 	"github.com/komem3/fing/filter"
 )
 
-var tmpDir = os.TempDir()
-
-var domains = strings.Split(tmpDir, string(filepath.Separator))
+var domains = []string{"root", "sub"}
 
 var extactTests = []struct {
 	name   string
@@ -33,12 +31,12 @@ var extactTests = []struct {
 		},
 		&filter.Gitignore{
 			PathMatchers: []gitignore.Pattern{
-				gitignore.ParsePattern(filepath.ToSlash("node_modules/**"), domains),
-				gitignore.ParsePattern(filepath.ToSlash("/vendor"), domains),
+				gitignore.ParsePattern("node_modules/**", domains),
+				gitignore.ParsePattern("/vendor", domains),
 				gitignore.ParsePattern("*.jpg", domains),
 				gitignore.ParsePattern("!*.txt", domains),
 				gitignore.ParsePattern("!sample.png", domains),
-				gitignore.ParsePattern("!"+filepath.ToSlash("/root.png"), domains),
+				gitignore.ParsePattern("!/root.png", domains),
 			},
 		},
 	},
@@ -54,17 +52,8 @@ func TestNewGitIgnore(t *testing.T) {
 		tt := tt
 		t.Run(tt.name, func(t *testing.T) {
 			t.Parallel()
-			tmp, err := os.CreateTemp(tmpDir, "testgitignore*")
-			if err != nil {
-				t.Fatal(err)
-			}
-			t.Cleanup(func() { os.Remove(tmp.Name()) })
-			if _, err := tmp.WriteString(strings.Join(tt.data, "\n")); err != nil {
-				t.Fatal(err)
-			}
-			tmp.Close()
-
-			ignores, err := filter.NewGitIgnore(tmpDir, filepath.Join(tmpDir, filepath.Base(tmp.Name())))
+			fsys := fstest.MapFS{"root/sub/.gitignore": {Data: []byte(strings.Join(tt.data, "\n"))}}
+			ignores, err := filter.NewGitIgnoreFS(fsys, "root/sub", "root/sub/.gitignore")
 			if err != nil {
 				t.Fatal(err)
 			}
@@ -0,0 +1,201 @@
+package filter
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/format/gitignore"
+)
+
+type GitStatusType int
+
+const (
+	GitModified GitStatusType = iota
+	GitUntracked
+	GitStaged
+	GitIgnored
+)
+
+type GitStatus struct {
+	typ   GitStatusType
+	repos *GitRepositories
+}
+
+// GitRepositories caches the status of repositories found while walking.
+// The status of each repository is computed once and shared by all GitStatus filters.
+type GitRepositories struct {
+	cwd   string
+	err   error
+	mutex sync.Mutex
+	roots map[string]string
+	repos map[string]*gitRepository
+}
+
+type gitRepository struct {
+	once    sync.Once
+	status  git.Status
+	tracked map[string]struct{}
+	ignore  gitignore.Matcher
+	err     error
+}
+
+var _ FileExp = (*GitStatus)(nil)
+
+func NewGitRepositories() *GitRepositories {
+	cwd, err := os.Getwd()
+	return &GitRepositories{
+		cwd:   cwd,
+		err:   err,
+		roots: make(map[string]string),
+		repos: make(map[string]*gitRepository),
+	}
+}
+
+func NewGitStatus(typ GitStatusType, repos *GitRepositories) *GitStatus {
+	return &GitStatus{typ: typ, repos: repos}
+}
+
+func (g *GitStatus) Match(path string, info fs.DirEntry) (bool, error) {
+	if !filepath.IsAbs(path) {
+		if g.repos.err != nil {
+			return false, g.repos.err
+		}
+		path = filepath.Join(g.repos.cwd, path)
+	}
+	// The entry belongs to the repository of its parent directory.
+	// This makes a nested repository or submodule directory itself be looked up in the outer repository.
+	root := g.repos.findRoot(filepath.Dir(path))
+	if root == "" {
+		return false, nil
+	}
+	repo := g.repos.load(root)
+	if repo.err != nil {
+		return false, repo.err
+	}
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return false, err
+	}
+	rel = filepath.ToSlash(rel)
+
+	switch g.typ {
+	case GitModified:
+		if info.IsDir() {
+			return false, nil
+		}
+		st, ok := repo.status[rel]
+		return ok && st.Worktree == git.Modified, nil
+	case GitUntracked:
+		if info.IsDir() {
+			return false, nil
+		}
+		st, ok := repo.status[rel]
+		return ok && st.Worktree == git.Untracked, nil
+	case GitStaged:
+		if info.IsDir() {
+			return false, nil
+		}
+		st, ok := repo.status[rel]
+		return ok && st.Staging != git.Unmodified && st.Staging != git.Untracked, nil
+	case GitIgnored:
+		if _, ok := repo.tracked[rel]; ok {
+			return false, nil
+		}
+		return repo.ignore.Match(strings.Split(rel, "/"), info.IsDir()), nil
+	}
+	panic("invalid git status type")
+}
+
+// cost is high because the status is read from the repository.
+func (*GitStatus) cost() int { return contentCost }
+
+func (g *GitStatus) String() string {
+	switch g.typ {
+	case GitModified:
+		return "git(modified)"
+	case GitUntracked:
+		return "git(untracked)"
+	case GitStaged:
+		return "git(staged)"
+	case GitIgnored:
+		return "git(ignored)"
+	}
+	return fmt.Sprintf("git(%d)", g.typ)
+}
+
+// findRoot returns the nearest directory containing .git, or empty string.
+func (r *GitRepositories) findRoot(dir string) string {
+	r.mutex.Lock()
+	root, ok := r.roots[dir]
+	r.mutex.Unlock()
+	if ok {
+		return root
+	}
+
+	if _, err := os.Lstat(filepath.Join(dir, git.GitDirName)); err == nil {
+		root = dir
+	} else if parent := filepath.Dir(dir); parent != dir {
+		root = r.findRoot(parent)
+	}
+
+	r.mutex.Lock()
+	r.roots[dir] = root
+	r.mutex.Unlock()
+	return root
+}
+
+func (r *GitRepositories) load(root string) *gitRepository {
+	r.mutex.Lock()
+	repo, ok := r.repos[root]
+	if !ok {
+		repo = new(gitRepository)
+		r.repos[root] = repo
+	}
+	r.mutex.Unlock()
+
+	repo.once.Do(func() {
+		repo.err = repo.open(root)
+	})
+	return repo
+}
+
+func (g *gitRepository) open(root string) error {
+	repo, err := git.PlainOpen(root)
+	if err != nil {
+		if errors.Is(err, git.ErrRepositoryNotExists) {
+			g.ignore = gitignore.NewMatcher(nil)
+			return nil
+		}
+		return fmt.Errorf("open repository %s: %w", root, err)
+	}
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return fmt.Errorf("open worktree %s: %w", root, err)
+	}
+	g.status, err = worktree.Status()
+	if err != nil {
+		return fmt.Errorf("git status %s: %w", root, err)
+	}
+
+	idx, err := repo.Storer.Index()
+	if err != nil {
+		return fmt.Errorf("read index %s: %w", root, err)
+	}
+	g.tracked = make(map[string]struct{}, len(idx.Entries))
+	for _, e := range idx.Entries {
+		g.tracked[e.Name] = struct{}{}
+	}
+
+	patterns, err := gitignore.ReadPatterns(worktree.Filesystem, nil)
+	if err != nil {
+		return fmt.Errorf("read gitignore %s: %w", root, err)
+	}
+	g.ignore = gitignore.NewMatcher(append(patterns, worktree.Excludes...))
+	return nil
+}
@@ -0,0 +1,104 @@
+package filter_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/komem3/fing/filter"
+)
+
+func TestGitStatus_Match(t *testing.T) {
+	root := t.TempDir()
+	writeFile := func(name, data string) {
+		t.Helper()
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	commit := func(dir string, files ...string) {
+		t.Helper()
+		repo, err := git.PlainInit(dir, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		worktree, err := repo.Worktree()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, f := range files {
+			if _, err := worktree.Add(f); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if _, err := worktree.Commit("init", &git.CommitOptions{
+			Author: &object.Signature{Name: "fing", Email: "fing@example.com", When: time.Now()},
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	writeFile(".gitignore", "*.log\n")
+	writeFile("clean.txt", "clean")
+	writeFile("modified.txt", "before")
+	writeFile("staged.txt", "before")
+	writeFile("nested/inner.txt", "inner")
+	commit(root, ".gitignore", "clean.txt", "modified.txt", "staged.txt")
+	commit(filepath.Join(root, "nested"), "inner.txt")
+
+	writeFile("modified.txt", "after")
+	writeFile("staged.txt", "after")
+	writeFile("untracked.txt", "new")
+	writeFile("debug.log", "log")
+	writeFile("nested/inner.txt", "changed")
+	repo, err := git.PlainOpen(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	worktree, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := worktree.Add("staged.txt"); err != nil {
+		t.Fatal(err)
+	}
+
+	repos := filter.NewGitRepositories()
+	for _, tt := range []struct {
+		typ   filter.GitStatusType
+		file  string
+		match bool
+	}{
+		{filter.GitModified, "modified.txt", true},
+		{filter.GitModified, "clean.txt", false},
+		{filter.GitModified, "staged.txt", false},
+		{filter.GitModified, "nested/inner.txt", true},
+		{filter.GitStaged, "staged.txt", true},
+		{filter.GitStaged, "modified.txt", false},
+		{filter.GitUntracked, "untracked.txt", true},
+		{filter.GitUntracked, "clean.txt", false},
+		{filter.GitUntracked, "nested/inner.txt", false},
+		{filter.GitIgnored, "debug.log", true},
+		{filter.GitIgnored, "clean.txt", false},
+	} {
+		tt := tt
+		t.Run(filter.NewGitStatus(tt.typ, repos).String()+"/"+tt.file, func(t *testing.T) {
+			t.Parallel()
+			match, err := filter.NewGitStatus(tt.typ, repos).
+				Match(filepath.Join(root, filepath.FromSlash(tt.file)), &mockDirFileInfo{name: filepath.Base(tt.file)})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if match != tt.match {
+				t.Errorf("match want %t, but got %t", tt.match, match)
+			}
+		})
+	}
+}
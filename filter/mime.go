@@ -0,0 +1,210 @@
+package filter
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/komem3/glob"
+)
+
+// sniffLen is the number of bytes to detect the content type, which is the same as net/http.
+// It covers the header of tar archives, which has the magic at 257.
+const sniffLen = 512
+
+type (
+	Mime struct{ *glob.Glob }
+	Kind string
+)
+
+const (
+	ImageKind   Kind = "image"
+	VideoKind   Kind = "video"
+	AudioKind   Kind = "audio"
+	ArchiveKind Kind = "archive"
+	TextKind    Kind = "text"
+	BinaryKind  Kind = "binary"
+)
+
+var (
+	_ FileExp = (*Mime)(nil)
+	_ FileExp = Kind("")
+)
+
+func NewMime(pattern string) (*Mime, error) {
+	glob, err := glob.Compile(strings.ToLower(pattern))
+	if err != nil {
+		return nil, err
+	}
+	return &Mime{Glob: glob}, nil
+}
+
+func NewKind(kind string) (Kind, error) {
+	switch k := Kind(kind); k {
+	case ImageKind, VideoKind, AudioKind, ArchiveKind, TextKind, BinaryKind:
+		return k, nil
+	}
+	return "", fmt.Errorf("%s is invalid kind. Support image, video, audio, archive, text and binary", kind)
+}
+
+func (m *Mime) Match(path string, info fs.DirEntry) (bool, error) {
+	if !info.Type().IsRegular() {
+		return false, nil
+	}
+	typ, err := DetectContentType(path, info)
+	if err != nil {
+		return false, err
+	}
+	return m.MatchString(typ), nil
+}
+
+// Match reports whether the content of the file is the kind.
+// Binary matches all files which are not text.
+func (k Kind) Match(path string, info fs.DirEntry) (bool, error) {
+	if !info.Type().IsRegular() {
+		return false, nil
+	}
+	typ, err := DetectContentType(path, info)
+	if err != nil {
+		return false, err
+	}
+	if k == BinaryKind {
+		return kindOf(typ) != TextKind, nil
+	}
+	return kindOf(typ) == k, nil
+}
+
+func (*Mime) cost() int { return contentCost }
+
+func (Kind) cost() int { return contentCost }
+
+func (m *Mime) String() string {
+	return fmt.Sprintf("mime(%s)", m.Glob)
+}
+
+func (k Kind) String() string {
+	return fmt.Sprintf("kind(%s)", string(k))
+}
+
+// DetectContentType returns the media type of the content without parameters.
+func DetectContentType(path string, info fs.DirEntry) (string, error) {
+	f, err := OpenFile(path, info)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	head := make([]byte, sniffLen)
+	n, err := io.ReadFull(f, head)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return "", err
+	}
+	return detectContentType(head[:n]), nil
+}
+
+type signature struct {
+	offset int
+	magic  string
+	typ    string
+	// valid checks the rest of the header if the magic is too short to trust.
+	valid func(head []byte) bool
+}
+
+// signatures are checked before http.DetectContentType, which doesn't know these types.
+var signatures = []signature{
+	{0, "\x7fELF", "application/x-executable", nil},
+	{0, "\xfe\xed\xfa\xce", "application/x-mach-binary", nil},
+	{0, "\xfe\xed\xfa\xcf", "application/x-mach-binary", nil},
+	{0, "\xce\xfa\xed\xfe", "application/x-mach-binary", nil},
+	{0, "\xcf\xfa\xed\xfe", "application/x-mach-binary", nil},
+	{0, "\xca\xfe\xba\xbe", "application/java-vm", nil},
+	{0, "MZ", "application/vnd.microsoft.portable-executable", isPE},
+	{0, "\x1f\x8b", "application/gzip", nil},
+	{0, "BZh", "application/x-bzip2", isBzip2},
+	{0, "\xfd7zXZ\x00", "application/x-xz", nil},
+	{0, "\x28\xb5\x2f\xfd", "application/zstd", nil},
+	{0, "7z\xbc\xaf\x27\x1c", "application/x-7z-compressed", nil},
+	{257, "ustar", "application/x-tar", nil},
+	{0, "SQLite format 3\x00", "application/vnd.sqlite3", nil},
+	{0, "fLaC", "audio/flac", nil},
+	{0, "II*\x00", "image/tiff", nil},
+	{0, "MM\x00*", "image/tiff", nil},
+	{0, "8BPS", "image/vnd.adobe.photoshop", nil},
+	{4, "ftypM4A ", "audio/mp4", nil},
+	{4, "ftypqt  ", "video/quicktime", nil},
+	{4, "ftypheic", "image/heic", nil},
+	{4, "ftypheix", "image/heic", nil},
+	{4, "ftypmif1", "image/heif", nil},
+	{4, "ftypavif", "image/avif", nil},
+	{28, "\x01vorbis", "audio/ogg", nil},
+	{28, "OpusHead", "audio/ogg", nil},
+	{28, "\x80theora", "video/ogg", nil},
+}
+
+func detectContentType(head []byte) string {
+	for _, s := range signatures {
+		if len(head) >= s.offset+len(s.magic) && string(head[s.offset:s.offset+len(s.magic)]) == s.magic &&
+			(s.valid == nil || s.valid(head)) {
+			return s.typ
+		}
+	}
+	typ, _, err := mime.ParseMediaType(http.DetectContentType(head))
+	if err != nil {
+		return "application/octet-stream"
+	}
+	if strings.HasPrefix(typ, "text/") && isSVG(head) {
+		return "image/svg+xml"
+	}
+	return typ
+}
+
+// isPE reports whether the MZ header points to the PE header by e_lfanew.
+func isPE(head []byte) bool {
+	if len(head) < 0x40 {
+		return false
+	}
+	offset := int(binary.LittleEndian.Uint32(head[0x3c:]))
+	return offset >= 0x40 && offset <= len(head)-4 && string(head[offset:offset+4]) == "PE\x00\x00"
+}
+
+// isBzip2 reports whether "BZh" is followed by the block size and the magic of the first block.
+func isBzip2(head []byte) bool {
+	return len(head) >= 10 && '1' <= head[3] && head[3] <= '9' && string(head[4:10]) == "1AY&SY"
+}
+
+func isSVG(head []byte) bool {
+	head = bytes.TrimSpace(head)
+	if bytes.HasPrefix(head, []byte("<?xml")) {
+		return bytes.Contains(head, []byte("<svg"))
+	}
+	return bytes.HasPrefix(head, []byte("<svg"))
+}
+
+func kindOf(typ string) Kind {
+	major, minor, _ := strings.Cut(typ, "/")
+	switch major {
+	case "image":
+		return ImageKind
+	case "video":
+		return VideoKind
+	case "audio":
+		return AudioKind
+	case "text":
+		return TextKind
+	}
+	switch minor {
+	case "ogg":
+		return AudioKind
+	case "zip", "gzip", "x-gzip", "x-tar", "x-bzip2", "x-xz", "zstd", "x-7z-compressed", "x-rar-compressed", "vnd.rar":
+		return ArchiveKind
+	case "json", "xml", "javascript", "x-javascript":
+		return TextKind
+	}
+	return BinaryKind
+}
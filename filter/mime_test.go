@@ -0,0 +1,99 @@
+package filter_test
+
+import (
+	"io/fs"
+	"testing"
+
+	"github.com/komem3/fing/filter"
+)
+
+var (
+	jpegFile   = &mockContentFile{data: "\xff\xd8\xff\xe0\x00\x10JFIF\x00"}
+	pngFile    = &mockContentFile{data: "\x89PNG\x0d\x0a\x1a\x0a\x00\x00"}
+	gzipFile   = &mockContentFile{data: "\x1f\x8b\x08\x00"}
+	elfFile    = &mockContentFile{data: "\x7fELF\x02\x01\x01\x00"}
+	textFile   = &mockContentFile{data: "hello world\n"}
+	svgFile    = &mockContentFile{data: "<?xml version=\"1.0\"?>\n<svg xmlns=\"http://www.w3.org/2000/svg\"></svg>"}
+	m4aFile    = &mockContentFile{data: "\x00\x00\x00\x20ftypM4A \x00\x00\x00\x00"}
+	directory  = &mockContentFile{mockDirFileInfo: mockDirFileInfo{isDir: true, typ: fs.ModeDir}}
+	tarArchive = &mockContentFile{data: string(make([]byte, 257)) + "ustar\x0000"}
+	mzText     = &mockContentFile{data: "MZ is a text file, not an executable.\n"}
+	peFile     = &mockContentFile{data: "MZ" + string(make([]byte, 0x3a)) + "\x40\x00\x00\x00PE\x00\x00"}
+	bzhText    = &mockContentFile{data: "BZh is not bzip2.\n"}
+)
+
+func TestMime_Match(t *testing.T) {
+	for _, tt := range []struct {
+		pattern string
+		info    fs.DirEntry
+		match   bool
+	}{
+		{"image/*", jpegFile, true},
+		{"image/png", pngFile, true},
+		{"image/*", textFile, false},
+		{"image/svg+xml", svgFile, true},
+		{"application/gzip", gzipFile, true},
+		{"application/x-tar", tarArchive, true},
+		{"text/plain", textFile, true},
+		{"application/vnd.microsoft.portable-executable", peFile, true},
+		{"application/vnd.microsoft.portable-executable", mzText, false},
+		{"*", directory, false},
+	} {
+		tt := tt
+		t.Run(tt.pattern, func(t *testing.T) {
+			t.Parallel()
+			f, err := filter.NewMime(tt.pattern)
+			if err != nil {
+				t.Fatal(err)
+			}
+			match, err := f.Match("", tt.info)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if match != tt.match {
+				t.Errorf("match want %t, but got %t", tt.match, match)
+			}
+		})
+	}
+}
+
+func TestKind_Match(t *testing.T) {
+	for _, tt := range []struct {
+		name  string
+		kind  string
+		info  fs.DirEntry
+		match bool
+	}{
+		{"jpeg is image", "image", jpegFile, true},
+		{"svg is image", "image", svgFile, true},
+		{"m4a is audio", "audio", m4aFile, true},
+		{"gzip is archive", "archive", gzipFile, true},
+		{"text is text", "text", textFile, true},
+		{"text is not binary", "binary", textFile, false},
+		{"elf is binary", "binary", elfFile, true},
+		{"jpeg is binary", "binary", jpegFile, true},
+		{"directory is not binary", "binary", directory, false},
+		{"text starting with MZ is text", "text", mzText, true},
+		{"pe is binary", "binary", peFile, true},
+		{"text starting with BZh is not archive", "archive", bzhText, false},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			f, err := filter.NewKind(tt.kind)
+			if err != nil {
+				t.Fatal(err)
+			}
+			match, err := f.Match("", tt.info)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if match != tt.match {
+				t.Errorf("match want %t, but got %t", tt.match, match)
+			}
+		})
+	}
+	if _, err := filter.NewKind("document"); err == nil {
+		t.Error("NewKind want error, but got nil")
+	}
+}
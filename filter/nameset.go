@@ -0,0 +1,231 @@
+package filter
+
+import (
+	"bufio"
+	"fmt"
+	"io/fs"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/komem3/glob"
+)
+
+// NameSet matches file names with many glob patterns at once.
+// Patterns like "*.go" are looked up by the extension, and patterns without wildcards are looked up by the name.
+// The other patterns are combined into one regular expression.
+type NameSet struct {
+	label    string
+	patterns []string
+	fold     bool
+	exts     map[string]struct{}
+	names    map[string]struct{}
+	reg      *regexp.Regexp
+}
+
+var _ FileExp = (*NameSet)(nil)
+
+const globMeta = `*?[\`
+
+func NewNameSet(patterns ...string) (*NameSet, error) {
+	return newNameSet(patterns, false)
+}
+
+// NewINameSet is like NewNameSet, but the match is case insensitive.
+func NewINameSet(patterns ...string) (*NameSet, error) {
+	return newNameSet(patterns, true)
+}
+
+// NewNameSetFromFile reads patterns from the file.
+// Each line is a pattern, and empty lines and lines starting with '#' are skipped.
+func NewNameSetFromFile(path string) (*NameSet, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var patterns []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		patterns = append(patterns, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	n, err := NewNameSet(patterns...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	n.label = fmt.Sprintf("name-from(%s)", path)
+	return n, nil
+}
+
+func newNameSet(patterns []string, fold bool) (*NameSet, error) {
+	n := &NameSet{
+		patterns: patterns,
+		fold:     fold,
+		exts:     make(map[string]struct{}),
+		names:    make(map[string]struct{}),
+	}
+	var regs []string
+	for _, p := range patterns {
+		if fold {
+			p = strings.ToLower(p)
+		}
+		if ext, ok := literalExt(p); ok {
+			n.exts[ext] = struct{}{}
+			continue
+		}
+		if !strings.ContainsAny(p, globMeta) {
+			n.names[p] = struct{}{}
+			continue
+		}
+		p = escapeBackSlash(p)
+		// validate the pattern in the same way as FileName.
+		if _, err := glob.Compile(p); err != nil {
+			return nil, err
+		}
+		regs = append(regs, globToRegexp(p))
+	}
+	if len(regs) > 0 {
+		flags := "(?s)"
+		if fold {
+			flags = "(?is)"
+		}
+		reg, err := regexp.Compile(flags + "^(?:" + strings.Join(regs, "|") + ")$")
+		if err != nil {
+			return nil, err
+		}
+		n.reg = reg
+	}
+	return n, nil
+}
+
+// literalExt returns ".ext" if pattern is "*.ext" and ext has no wildcard.
+func literalExt(pattern string) (string, bool) {
+	if !strings.HasPrefix(pattern, "*.") || strings.ContainsAny(pattern[1:], globMeta) {
+		return "", false
+	}
+	return pattern[1:], true
+}
+
+// globToRegexp translates the glob pattern compiled by glob.Compile to a regular expression.
+func globToRegexp(pattern string) string {
+	var (
+		buf    strings.Builder
+		runes  = []rune(pattern)
+		escape bool
+	)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case escape:
+			buf.WriteString(regexp.QuoteMeta(string(r)))
+			escape = false
+		case r == '\\':
+			escape = true
+		case r == '*':
+			if i > 0 && runes[i-1] == '*' {
+				continue
+			}
+			buf.WriteString(".*")
+		case r == '?':
+			buf.WriteString(".")
+		case r == '[':
+			end := indexCloseSquare(runes[i:])
+			buf.WriteString(bracketToRegexp(runes[i+1 : i+end]))
+			i += end
+		default:
+			buf.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	return buf.String()
+}
+
+// bracketToRegexp translates the content of a bracket expression.
+// Characters are literal except a leading '^', ranges and character classes like [:alpha:].
+func bracketToRegexp(content []rune) string {
+	var buf strings.Builder
+	buf.WriteByte('[')
+	for i := 0; i < len(content); i++ {
+		r := content[i]
+		switch {
+		case r == '^' && i == 0:
+			buf.WriteRune(r)
+		case r == '[':
+			end := indexCloseSquare(content[i:])
+			buf.WriteString(string(content[i : i+end+1]))
+			i += end
+		case r == '\\' || r == ']' || r == '^' || r == '[':
+			buf.WriteByte('\\')
+			buf.WriteRune(r)
+		default:
+			buf.WriteRune(r)
+		}
+	}
+	buf.WriteByte(']')
+	return buf.String()
+}
+
+func indexCloseSquare(runes []rune) int {
+	var (
+		escape bool
+		depth  int
+	)
+	for i, r := range runes {
+		switch {
+		case !escape && r == '\\':
+			escape = true
+			continue
+		case !escape && r == '[':
+			depth++
+		case !escape && r == ']':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+		escape = false
+	}
+	return -1
+}
+
+func (n *NameSet) Match(_ string, info fs.DirEntry) (bool, error) {
+	return n.MatchString(info.Name()), nil
+}
+
+func (n *NameSet) MatchString(name string) bool {
+	if n.fold {
+		name = strings.ToLower(name)
+	}
+	if _, ok := n.names[name]; ok {
+		return true
+	}
+	if len(n.exts) > 0 {
+		// Check every extension to support multiple extensions like *.tar.gz.
+		for i := 0; i < len(name); i++ {
+			if name[i] != '.' {
+				continue
+			}
+			if _, ok := n.exts[name[i:]]; ok {
+				return true
+			}
+		}
+	}
+	return n.reg != nil && n.reg.MatchString(name)
+}
+
+func (n *NameSet) String() string {
+	if n.label != "" {
+		return n.label
+	}
+	if n.fold {
+		return fmt.Sprintf("inames(%s)", strings.Join(n.patterns, ","))
+	}
+	return fmt.Sprintf("names(%s)", strings.Join(n.patterns, ","))
+}
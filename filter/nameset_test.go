@@ -0,0 +1,173 @@
+package filter_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/komem3/fing/filter"
+)
+
+func TestNameSet_Match(t *testing.T) {
+	t.Parallel()
+	set, err := filter.NewNameSet("*.go", "*.tar.gz", "Makefile", "Dockerfile.*", "*_test.[ch]")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		name  string
+		match bool
+	}{
+		{"main.go", true},
+		{"main.go.bak", false},
+		{"release.tar.gz", true},
+		{"release.gz", false},
+		{"Makefile", true},
+		{"makefile", false},
+		{"Dockerfile.dev", true},
+		{"util_test.c", true},
+		{"util_test.cpp", false},
+		{".go", true},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if match, _ := set.Match("", &mockDirFileInfo{name: tt.name}); match != tt.match {
+				t.Errorf("match want %t, but got %t", tt.match, match)
+			}
+		})
+	}
+}
+
+func TestNameSet_Match_glob(t *testing.T) {
+	t.Parallel()
+	for _, tt := range []struct {
+		pattern string
+		name    string
+		match   bool
+	}{
+		{"a?c", "abc", true},
+		{"a?c", "ac", false},
+		{"*.min.*", "app.min.js", true},
+		{"[a-c]x*", "bxy", true},
+		{"[^a-c]x*", "bxy", false},
+		{"[[:digit:]].txt", "1.txt", true},
+		{"[[:digit:]].txt", "a.txt", false},
+		{"a+b(*", "a+b(c", true},
+		{"a+b(*", "aab(c", false},
+		{"*.[ch]", "main.c", true},
+	} {
+		tt := tt
+		t.Run(tt.pattern+" "+tt.name, func(t *testing.T) {
+			t.Parallel()
+			// compare with FileName which uses the same glob.
+			f, err := filter.NewFileName(tt.pattern)
+			if err != nil {
+				t.Fatal(err)
+			}
+			set, err := filter.NewNameSet(tt.pattern, "unmatched")
+			if err != nil {
+				t.Fatal(err)
+			}
+			info := &mockDirFileInfo{name: tt.name}
+			if match, _ := f.Match("", info); match != tt.match {
+				t.Errorf("FileName match want %t, but got %t", tt.match, match)
+			}
+			if match, _ := set.Match("", info); match != tt.match {
+				t.Errorf("NameSet match want %t, but got %t", tt.match, match)
+			}
+		})
+	}
+}
+
+func TestINameSet_Match(t *testing.T) {
+	t.Parallel()
+	set, err := filter.NewINameSet("*.JPG", "readme", "img_*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, match := range map[string]bool{
+		"a.jpg":     true,
+		"README":    true,
+		"IMG_1.png": true,
+		"a.png":     false,
+	} {
+		if got, _ := set.Match("", &mockDirFileInfo{name: name}); got != match {
+			t.Errorf("%s: match want %t, but got %t", name, match, got)
+		}
+	}
+	if _, err := filter.NewNameSet("[a-"); err == nil {
+		t.Errorf("invalid glob want error")
+	}
+}
+
+func TestNewNameSetFromFile(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "names")
+	if err := os.WriteFile(path, []byte("# images\n*.jpg\r\n\nlogo-*\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	set, err := filter.NewNameSetFromFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, match := range map[string]bool{
+		"a.jpg":    true,
+		"logo-1.x": true,
+		"# images": false,
+		"":         false,
+	} {
+		if got, _ := set.Match("", &mockDirFileInfo{name: name}); got != match {
+			t.Errorf("%q: match want %t, but got %t", name, match, got)
+		}
+	}
+	if want := "name-from(" + path + ")"; set.String() != want {
+		t.Errorf("String want %s, but got %s", want, set)
+	}
+}
+
+func TestFileTypes(t *testing.T) {
+	t.Parallel()
+	types := filter.DefaultFileTypes()
+	if err := types.Add("web:*.html,*.css"); err != nil {
+		t.Fatal(err)
+	}
+	if err := types.Add("go:*.tmpl"); err != nil {
+		t.Fatal(err)
+	}
+	for _, def := range []string{"web", "web:", ":*.go", "web:*.html,"} {
+		if err := types.Add(def); err == nil {
+			t.Errorf("%s want error", def)
+		}
+	}
+	if _, err := types.Matcher("unknown"); err == nil {
+		t.Errorf("unknown file type want error")
+	}
+	if _, ok := filter.DefaultFileTypes()["web"]; ok {
+		t.Errorf("DefaultFileTypes must return a copy")
+	}
+
+	for _, tt := range []struct {
+		typ   string
+		name  string
+		match bool
+	}{
+		{"go", "main.go", true},
+		{"go", "page.tmpl", true},
+		{"go", "main.rs", false},
+		{"web", "index.html", true},
+		{"make", "Makefile", true},
+		{"docker", "Dockerfile", true},
+	} {
+		set, err := types.Matcher(tt.typ)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if match, _ := set.Match("", &mockDirFileInfo{name: tt.name}); match != tt.match {
+			t.Errorf("%s %s: match want %t, but got %t", tt.typ, tt.name, tt.match, match)
+		}
+		if want := "filetype(" + tt.typ + ")"; set.String() != want {
+			t.Errorf("String want %s, but got %s", want, set.String())
+		}
+	}
+}
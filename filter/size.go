@@ -3,7 +3,9 @@ package filter
 import (
 	"fmt"
 	"io/fs"
+	"math"
 	"strconv"
+	"strings"
 )
 
 type CmpOption int
@@ -12,19 +14,50 @@ const (
 	EqualCmpOption CmpOption = iota
 	GreaterCmpOption
 	LessCmpOption
+	// RangeCmpOption matches sizes from Size to Max inclusive.
+	RangeCmpOption
 )
 
 type Size struct {
 	Size int64
 	Opt  CmpOption
+	// Max is the upper bound of RangeCmpOption.
+	Max int64
+	// Unit is the unit of Size. If Round is set, file sizes are rounded up to the unit like find,
+	// so that "1M" matches files from 1 byte to 1MiB and "-1M" matches only empty files.
+	Unit  int64
+	Round bool
 }
 
 var _ FileExp = (*Size)(nil)
 
+// NewSize parses [+|-]n[unit] or n[unit]..n[unit], where either side of the range may be omitted.
 func NewSize(str string) (*Size, error) {
 	if len(str) == 0 {
 		return nil, fmt.Errorf("missing argument of size")
 	}
+	if min, max, ok := strings.Cut(str, ".."); ok {
+		if min == "" && max == "" {
+			return nil, fmt.Errorf("%s is invalid size argument", str)
+		}
+		size := &Size{Opt: RangeCmpOption, Unit: 1, Max: math.MaxInt64}
+		var err error
+		if min != "" {
+			if size.Size, size.Unit, err = parseSize(min, blockSize); err != nil {
+				return nil, err
+			}
+		}
+		if max != "" {
+			if size.Max, _, err = parseSize(max, blockSize); err != nil {
+				return nil, err
+			}
+		}
+		if size.Size > size.Max {
+			return nil, fmt.Errorf("%s is empty range of size", str)
+		}
+		return size, nil
+	}
+
 	var (
 		opt CmpOption
 		s   = str[:]
@@ -43,53 +76,105 @@ func NewSize(str string) (*Size, error) {
 		opt = EqualCmpOption
 	}
 
-	var size int64
-	switch s[len(s)-1] {
-	case 'c':
-		psize, err := strconv.ParseInt(s[:len(s)-1], 10, 64)
-		if err != nil {
-			return nil, err
-		}
-		size = psize
-	case 'k':
-		psize, err := strconv.ParseInt(s[:len(s)-1], 10, 64)
-		if err != nil {
-			return nil, err
-		}
-		size = psize * 1024
-	case 'M':
-		psize, err := strconv.ParseInt(s[:len(s)-1], 10, 64)
-		if err != nil {
-			return nil, err
-		}
-		size = psize * 1024 * 1024
-	case 'G':
-		psize, err := strconv.ParseInt(s[:len(s)-1], 10, 64)
-		if err != nil {
-			return nil, err
-		}
-		size = psize * 1024 * 1024 * 1024
-	default:
-		return nil, fmt.Errorf("%c is invalid unit of size", s[len(s)-1])
+	size, unit, err := parseSize(s, blockSize)
+	if err != nil {
+		return nil, err
 	}
 	return &Size{
 		Size: size,
 		Opt:  opt,
+		Unit: unit,
 	}, nil
 }
 
+// SizeUnits are the units of ParseSize.
+var SizeUnits = []string{"b", "c", "w", "k", "M", "G", "T", "P", "kB", "MB", "GB", "TB", "PB"}
+
+var sizeUnitBytes = map[string]int64{
+	"b": blockSize, "c": 1, "w": 2,
+	"k": 1 << 10, "M": 1 << 20, "G": 1 << 30, "T": 1 << 40, "P": 1 << 50,
+	"kB": 1e3, "MB": 1e6, "GB": 1e9, "TB": 1e12, "PB": 1e15,
+}
+
+// blockSize is the unit of NewSize without a unit like find.
+const blockSize = 512
+
+// ParseSize parses a size with a unit.
+// The unit is b(for 512-byte blocks), c(for bytes), w(for 2-byte words),
+// k, M, G, T, P(for KiB, MiB, GiB, TiB, PiB) or kB, MB, GB, TB, PB(for 1000, 1000^2, ... bytes).
+func ParseSize(s string) (int64, error) {
+	size, _, err := parseSize(s, 0)
+	return size, err
+}
+
+// parseSize returns the size in bytes and the unit.
+// defaultUnit is used when s has no unit, and the unit is required if it is 0.
+func parseSize(s string, defaultUnit int64) (size, unit int64, err error) {
+	if len(s) == 0 {
+		return 0, 0, fmt.Errorf("missing argument of size")
+	}
+	split := strings.IndexFunc(s, func(r rune) bool { return r < '0' || r > '9' })
+	if split < 0 {
+		split = len(s)
+	}
+	unit, ok := sizeUnitBytes[s[split:]]
+	if split == len(s) {
+		unit, ok = defaultUnit, defaultUnit > 0
+	}
+	if !ok {
+		if split == len(s) {
+			return 0, 0, fmt.Errorf("missing unit of size %s", s)
+		}
+		return 0, 0, fmt.Errorf("%s is invalid unit of size", s[split:])
+	}
+	n, err := strconv.ParseInt(s[:split], 10, 64)
+	if err != nil {
+		return 0, 0, err
+	}
+	if n > math.MaxInt64/unit {
+		return 0, 0, fmt.Errorf("%s is too large size", s)
+	}
+	return n * unit, unit, nil
+}
+
+// bounds returns the inclusive range of matched file sizes.
+func (s *Size) bounds() (min, max int64) {
+	// lower is the smallest size rounded up to Size.
+	lower := s.Size
+	if s.Round && s.Size > 0 {
+		lower = s.Size - s.Unit + 1
+	}
+	switch s.Opt {
+	case GreaterCmpOption:
+		return s.Size + 1, math.MaxInt64
+	case LessCmpOption:
+		return 0, lower - 1
+	case RangeCmpOption:
+		return lower, s.Max
+	}
+	return lower, s.Size
+}
+
 func (s *Size) Match(_ string, entry fs.DirEntry) (bool, error) {
 	info, err := entry.Info()
 	if err != nil {
 		return false, err
 	}
+	min, max := s.bounds()
+	return min <= info.Size() && info.Size() <= max, nil
+}
+
+func (s *Size) String() string {
 	switch s.Opt {
-	case EqualCmpOption:
-		return info.Size() == int64(s.Size), nil
 	case GreaterCmpOption:
-		return info.Size() > int64(s.Size), nil
+		return fmt.Sprintf("size(+%dc)", s.Size)
 	case LessCmpOption:
-		return info.Size() < int64(s.Size), nil
+		return fmt.Sprintf("size(-%dc)", s.Size)
+	case RangeCmpOption:
+		if s.Max == math.MaxInt64 {
+			return fmt.Sprintf("size(%dc..)", s.Size)
+		}
+		return fmt.Sprintf("size(%dc..%dc)", s.Size, s.Max)
 	}
-	panic("invalid compare option")
+	return fmt.Sprintf("size(%dc)", s.Size)
 }
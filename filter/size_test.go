@@ -2,6 +2,7 @@ package filter_test
 
 import (
 	"io/fs"
+	"math"
 	"reflect"
 	"testing"
 
@@ -14,13 +15,26 @@ func TestNewSize(t *testing.T) {
 		size   *filter.Size
 		errMsg string
 	}{
-		{"+3c", &filter.Size{3, filter.GreaterCmpOption}, ""},
-		{"-3c", &filter.Size{3, filter.LessCmpOption}, ""},
-		{"1k", &filter.Size{1024, filter.EqualCmpOption}, ""},
-		{"1M", &filter.Size{1048576, filter.EqualCmpOption}, ""},
-		{"1G", &filter.Size{1073741824, filter.EqualCmpOption}, ""},
-		{"1", nil, "1 is invalid unit of size"},
+		{"+3c", &filter.Size{Size: 3, Opt: filter.GreaterCmpOption, Unit: 1}, ""},
+		{"-3c", &filter.Size{Size: 3, Opt: filter.LessCmpOption, Unit: 1}, ""},
+		{"1k", &filter.Size{Size: 1024, Opt: filter.EqualCmpOption, Unit: 1024}, ""},
+		{"1M", &filter.Size{Size: 1048576, Opt: filter.EqualCmpOption, Unit: 1048576}, ""},
+		{"1G", &filter.Size{Size: 1073741824, Opt: filter.EqualCmpOption, Unit: 1073741824}, ""},
+		{"2T", &filter.Size{Size: 2 << 40, Opt: filter.EqualCmpOption, Unit: 1 << 40}, ""},
+		{"1P", &filter.Size{Size: 1 << 50, Opt: filter.EqualCmpOption, Unit: 1 << 50}, ""},
+		{"3kB", &filter.Size{Size: 3000, Opt: filter.EqualCmpOption, Unit: 1000}, ""},
+		{"1MB", &filter.Size{Size: 1000000, Opt: filter.EqualCmpOption, Unit: 1000000}, ""},
+		{"1", &filter.Size{Size: 512, Opt: filter.EqualCmpOption, Unit: 512}, ""},
+		{"2b", &filter.Size{Size: 1024, Opt: filter.EqualCmpOption, Unit: 512}, ""},
+		{"3w", &filter.Size{Size: 6, Opt: filter.EqualCmpOption, Unit: 2}, ""},
+		{"10M..1G", &filter.Size{Size: 10 << 20, Opt: filter.RangeCmpOption, Max: 1 << 30, Unit: 1 << 20}, ""},
+		{"1k..", &filter.Size{Size: 1024, Opt: filter.RangeCmpOption, Max: math.MaxInt64, Unit: 1024}, ""},
+		{"..1k", &filter.Size{Opt: filter.RangeCmpOption, Max: 1024, Unit: 1}, ""},
+		{"1..2", &filter.Size{Size: 512, Opt: filter.RangeCmpOption, Max: 1024, Unit: 512}, ""},
 		{"1m", nil, "m is invalid unit of size"},
+		{"10000P", nil, "10000P is too large size"},
+		{"1G..1M", nil, "1G..1M is empty range of size"},
+		{"..", nil, ".. is invalid size argument"},
 		{"+", nil, "+ is invalid size argument"},
 		{"", nil, "missing argument of size"},
 	} {
@@ -49,25 +63,53 @@ func TestSize_Match(t *testing.T) {
 		match bool
 	}{
 		{
-			"equal", &filter.Size{1024, filter.EqualCmpOption},
+			"equal", &filter.Size{Size: 1024, Opt: filter.EqualCmpOption, Unit: 1},
 			&mockDirFileInfo{size: 1024}, true,
 		},
 		{
-			"greater", &filter.Size{1024, filter.GreaterCmpOption},
+			"greater", &filter.Size{Size: 1024, Opt: filter.GreaterCmpOption, Unit: 1},
 			&mockDirFileInfo{size: 1025}, true,
 		},
 		{
-			"less", &filter.Size{1024, filter.LessCmpOption},
+			"less", &filter.Size{Size: 1024, Opt: filter.LessCmpOption, Unit: 1},
 			&mockDirFileInfo{size: 1023}, true,
 		},
 		{
-			"not greater", &filter.Size{1024, filter.GreaterCmpOption},
+			"not greater", &filter.Size{Size: 1024, Opt: filter.GreaterCmpOption, Unit: 1},
 			&mockDirFileInfo{size: 1024}, false,
 		},
 		{
-			"not less", &filter.Size{1024, filter.LessCmpOption},
+			"not less", &filter.Size{Size: 1024, Opt: filter.LessCmpOption, Unit: 1},
 			&mockDirFileInfo{size: 1024}, false,
 		},
+		{
+			"range", &filter.Size{Size: 10, Opt: filter.RangeCmpOption, Max: 20, Unit: 1},
+			&mockDirFileInfo{size: 20}, true,
+		},
+		{
+			"not range", &filter.Size{Size: 10, Opt: filter.RangeCmpOption, Max: 20, Unit: 1},
+			&mockDirFileInfo{size: 9}, false,
+		},
+		{
+			"round equal", &filter.Size{Size: 1024, Opt: filter.EqualCmpOption, Unit: 1024, Round: true},
+			&mockDirFileInfo{size: 1}, true,
+		},
+		{
+			"not round equal", &filter.Size{Size: 1024, Opt: filter.EqualCmpOption, Unit: 1024, Round: true},
+			&mockDirFileInfo{size: 1025}, false,
+		},
+		{
+			"round less", &filter.Size{Size: 1024, Opt: filter.LessCmpOption, Unit: 1024, Round: true},
+			&mockDirFileInfo{size: 0}, true,
+		},
+		{
+			"not round less", &filter.Size{Size: 1024, Opt: filter.LessCmpOption, Unit: 1024, Round: true},
+			&mockDirFileInfo{size: 1}, false,
+		},
+		{
+			"round range", &filter.Size{Size: 2048, Opt: filter.RangeCmpOption, Max: 4096, Unit: 1024, Round: true},
+			&mockDirFileInfo{size: 1025}, true,
+		},
 	} {
 		tt := tt
 		t.Run(tt.name, func(t *testing.T) {
@@ -78,3 +120,15 @@ func TestSize_Match(t *testing.T) {
 		})
 	}
 }
+
+func TestParseSize(t *testing.T) {
+	for arg, want := range map[string]int64{"1k": 1024, "2b": 1024, "3c": 3, "1MB": 1e6} {
+		if got, err := filter.ParseSize(arg); err != nil || got != want {
+			t.Errorf("ParseSize(%s) want %d, but got %d (%v)", arg, want, got, err)
+		}
+	}
+	// unlike -size, the unit is required.
+	if _, err := filter.ParseSize("1024"); err == nil || err.Error() != "missing unit of size 1024" {
+		t.Errorf("ParseSize without a unit want error, but got %v", err)
+	}
+}
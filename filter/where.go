@@ -0,0 +1,663 @@
+package filter
+
+import (
+	"fmt"
+	"io/fs"
+	"math"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/komem3/glob"
+)
+
+// Where matches files by an expression of a small typed language, such as
+//
+//	size > 1M && ext in ["go", "mod"] && mtime > now - 24h
+//
+// The expression is parsed and type checked by NewWhere.
+type Where struct {
+	src  string
+	eval whereEval
+}
+
+var _ FileExp = (*Where)(nil)
+
+// Depther is implemented by entries which know the depth from the starting-point.
+type Depther interface {
+	Depth() int
+}
+
+type whereType int
+
+const (
+	boolType whereType = iota
+	intType
+	stringType
+	timeType
+	durationType
+	stringListType
+	intListType
+)
+
+func (t whereType) String() string {
+	return [...]string{"bool", "int", "string", "time", "duration", "list of string", "list of int"}[t]
+}
+
+// whereEnv is the file evaluated by the expression.
+type whereEnv struct {
+	path  string
+	entry fs.DirEntry
+	info  fs.FileInfo
+}
+
+func (e *whereEnv) fileInfo() (fs.FileInfo, error) {
+	if e.info == nil {
+		info, err := e.entry.Info()
+		if err != nil {
+			return nil, err
+		}
+		e.info = info
+	}
+	return e.info, nil
+}
+
+// whereEval evaluates an expression. The type of the result is decided by the type checking:
+// bool, int64, string, time.Time, time.Duration, []string or []int64.
+type whereEval func(env *whereEnv) (any, error)
+
+type whereExpr struct {
+	typ  whereType
+	eval whereEval
+	// constant is true if the expression doesn't depend on files.
+	constant bool
+}
+
+type whereField struct {
+	typ whereType
+	get whereEval
+}
+
+func infoField(typ whereType, get func(info fs.FileInfo) (any, error)) whereField {
+	return whereField{typ: typ, get: func(env *whereEnv) (any, error) {
+		info, err := env.fileInfo()
+		if err != nil {
+			return nil, err
+		}
+		return get(info)
+	}}
+}
+
+var whereFields = map[string]whereField{
+	"name": {stringType, func(env *whereEnv) (any, error) { return env.entry.Name(), nil }},
+	"path": {stringType, func(env *whereEnv) (any, error) { return env.path, nil }},
+	"ext": {stringType, func(env *whereEnv) (any, error) {
+		return strings.TrimPrefix(filepath.Ext(env.entry.Name()), "."), nil
+	}},
+	"type": {stringType, func(env *whereEnv) (any, error) { return typeName(env.entry.Type()), nil }},
+	"depth": {intType, func(env *whereEnv) (any, error) {
+		d, ok := env.entry.(Depther)
+		if !ok {
+			return nil, fmt.Errorf("depth of %s is unknown", env.path)
+		}
+		return int64(d.Depth()), nil
+	}},
+	"size":  infoField(intType, func(info fs.FileInfo) (any, error) { return info.Size(), nil }),
+	"mode":  infoField(intType, func(info fs.FileInfo) (any, error) { return int64(info.Mode().Perm()), nil }),
+	"mtime": infoField(timeType, func(info fs.FileInfo) (any, error) { return info.ModTime(), nil }),
+	"atime": infoField(timeType, func(info fs.FileInfo) (any, error) {
+		t, ok := accessTime(info)
+		if !ok {
+			return nil, fmt.Errorf("atime is not supported on this platform or for this file")
+		}
+		return t, nil
+	}),
+	"ctime": infoField(timeType, func(info fs.FileInfo) (any, error) {
+		t, ok := changeTime(info)
+		if !ok {
+			return nil, fmt.Errorf("ctime is not supported on this platform or for this file")
+		}
+		return t, nil
+	}),
+	"uid": infoField(intType, func(info fs.FileInfo) (any, error) {
+		uid, ok := fileUID(info)
+		if !ok {
+			return nil, fmt.Errorf("uid is not supported on this platform")
+		}
+		return uid, nil
+	}),
+}
+
+func typeName(mode fs.FileMode) string {
+	switch {
+	case mode.IsRegular():
+		return "file"
+	case mode.IsDir():
+		return "dir"
+	case mode&fs.ModeSymlink != 0:
+		return "symlink"
+	case mode&fs.ModeNamedPipe != 0:
+		return "pipe"
+	case mode&fs.ModeSocket != 0:
+		return "socket"
+	case mode&fs.ModeDevice != 0:
+		return "device"
+	}
+	return "other"
+}
+
+type whereFunc struct {
+	params []whereType
+	result whereType
+	// constants are the indexes of params which must be constant.
+	constants []int
+	bind      func(args []*whereExpr) (whereEval, error)
+}
+
+var whereFuncs = map[string]whereFunc{
+	"glob": {
+		params: []whereType{stringType, stringType}, result: boolType, constants: []int{1},
+		bind: func(args []*whereExpr) (whereEval, error) {
+			pattern, _ := args[1].eval(nil)
+			g, err := glob.Compile(escapeBackSlash(pattern.(string)))
+			if err != nil {
+				return nil, err
+			}
+			return func(env *whereEnv) (any, error) {
+				s, err := args[0].eval(env)
+				if err != nil {
+					return nil, err
+				}
+				return g.MatchString(s.(string)), nil
+			}, nil
+		},
+	},
+	"matches": {
+		params: []whereType{stringType, stringType}, result: boolType, constants: []int{1},
+		bind: func(args []*whereExpr) (whereEval, error) {
+			pattern, _ := args[1].eval(nil)
+			reg, err := regexp.Compile(pattern.(string))
+			if err != nil {
+				return nil, err
+			}
+			return func(env *whereEnv) (any, error) {
+				s, err := args[0].eval(env)
+				if err != nil {
+					return nil, err
+				}
+				return reg.MatchString(s.(string)), nil
+			}, nil
+		},
+	},
+	"contains": {
+		params: []whereType{stringType, stringType}, result: boolType,
+		bind: func(args []*whereExpr) (whereEval, error) {
+			return binaryEval(args[0], args[1], func(a, b any) (any, error) {
+				return strings.Contains(a.(string), b.(string)), nil
+			}), nil
+		},
+	},
+	"lower": {
+		params: []whereType{stringType}, result: stringType,
+		bind: func(args []*whereExpr) (whereEval, error) {
+			return func(env *whereEnv) (any, error) {
+				s, err := args[0].eval(env)
+				if err != nil {
+					return nil, err
+				}
+				return strings.ToLower(s.(string)), nil
+			}, nil
+		},
+	},
+	"date": {
+		params: []whereType{stringType}, result: timeType, constants: []int{0},
+		bind: func(args []*whereExpr) (whereEval, error) {
+			s, _ := args[0].eval(nil)
+			for _, layout := range []string{"2006-01-02", "2006-01-02T15:04", "2006-01-02T15:04:05", time.RFC3339} {
+				if t, err := time.ParseInLocation(layout, s.(string), time.Local); err == nil {
+					return func(*whereEnv) (any, error) { return t, nil }, nil
+				}
+			}
+			return nil, fmt.Errorf("%q is invalid date. Use 2006-01-02, 2006-01-02T15:04:05 or RFC 3339", s)
+		},
+	},
+}
+
+// NewWhere parses and type checks the expression of -where.
+//
+// Fields are name, path, ext, type, size, mode, mtime, atime, ctime, uid and depth.
+// Sizes such as 10k and 1M are ints, and durations such as 30m, 24h and 7d are subtracted from times like now - 7d.
+// Functions are glob(s, pattern), matches(s, regex), contains(s, sub), lower(s) and date("2006-01-02").
+func NewWhere(src string) (*Where, error) {
+	node, err := parseWhere(src)
+	if err != nil {
+		return nil, err
+	}
+	c := &whereCompiler{src: src, now: time.Now()}
+	expr, err := c.compile(node)
+	if err != nil {
+		return nil, err
+	}
+	if expr.typ != boolType {
+		return nil, c.errorf(node, "expression must be bool, but it is %s", expr.typ)
+	}
+	return &Where{src: src, eval: expr.eval}, nil
+}
+
+func (w *Where) Match(path string, info fs.DirEntry) (bool, error) {
+	match, err := w.eval(&whereEnv{path: path, entry: info})
+	if err != nil {
+		return false, err
+	}
+	return match.(bool), nil
+}
+
+func (w *Where) String() string {
+	return fmt.Sprintf("where(%s)", w.src)
+}
+
+type whereCompiler struct {
+	src string
+	now time.Time
+}
+
+func (c *whereCompiler) errorf(node *whereNode, format string, args ...any) error {
+	return &WhereError{Src: c.src, Pos: node.pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+func constant(typ whereType, v any) *whereExpr {
+	return &whereExpr{typ: typ, eval: func(*whereEnv) (any, error) { return v, nil }, constant: true}
+}
+
+// fold evaluates the expression now if it is constant.
+func fold(expr *whereExpr) (*whereExpr, error) {
+	if !expr.constant {
+		return expr, nil
+	}
+	v, err := expr.eval(nil)
+	if err != nil {
+		return nil, err
+	}
+	return constant(expr.typ, v), nil
+}
+
+func (c *whereCompiler) compile(node *whereNode) (*whereExpr, error) {
+	var (
+		expr *whereExpr
+		err  error
+	)
+	switch node.kind {
+	case literalNode:
+		return c.literal(node)
+	case stringNode:
+		return constant(stringType, node.text), nil
+	case identNode:
+		return c.ident(node)
+	case callNode:
+		expr, err = c.call(node)
+	case listNode:
+		return c.list(node)
+	case unaryNode:
+		expr, err = c.unary(node)
+	case binaryNode:
+		expr, err = c.binary(node)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if expr, err = fold(expr); err != nil {
+		return nil, c.errorf(node, "%v", err)
+	}
+	return expr, nil
+}
+
+var durationUnits = map[string]time.Duration{
+	"ms": time.Millisecond, "s": time.Second, "m": time.Minute, "h": time.Hour,
+	"d": 24 * time.Hour, "w": 7 * 24 * time.Hour,
+}
+
+func (c *whereCompiler) literal(node *whereNode) (*whereExpr, error) {
+	text := node.text
+	if i, err := strconv.ParseInt(text, 0, 64); err == nil {
+		return constant(intType, i), nil
+	}
+	split := strings.IndexFunc(text, func(r rune) bool { return r != '.' && (r < '0' || r > '9') })
+	if split > 0 {
+		num, unit := text[:split], text[split:]
+		n, err := strconv.ParseFloat(num, 64)
+		if err == nil {
+			// durations come first, because w is a week rather than a word here.
+			if u, ok := durationUnits[unit]; ok {
+				return constant(durationType, time.Duration(n*float64(u))), nil
+			}
+			if u, ok := sizeUnitBytes[unit]; ok {
+				size := n * float64(u)
+				if size > math.MaxInt64 {
+					return nil, c.errorf(node, "%s is too large", text)
+				}
+				return constant(intType, int64(math.Ceil(size))), nil
+			}
+			return nil, c.errorf(node, "unknown unit %q of %s. Sizes are b, c, k, M, G, T, P, kB, MB, GB, TB and PB, and durations are ms, s, m, h, d and w", unit, text)
+		}
+	}
+	return nil, c.errorf(node, "invalid number %s", text)
+}
+
+func (c *whereCompiler) ident(node *whereNode) (*whereExpr, error) {
+	switch node.text {
+	case "true", "false":
+		return constant(boolType, node.text == "true"), nil
+	case "now":
+		return constant(timeType, c.now), nil
+	}
+	if field, ok := whereFields[node.text]; ok {
+		return &whereExpr{typ: field.typ, eval: field.get}, nil
+	}
+	if _, ok := whereFuncs[node.text]; ok {
+		return nil, c.errorf(node, "%s is a function, call it like %s(...)", node.text, node.text)
+	}
+	msg := fmt.Sprintf("unknown field %q", node.text)
+	if s := suggest(node.text, whereNames()); s != "" {
+		msg += fmt.Sprintf(", did you mean %q?", s)
+	}
+	return nil, c.errorf(node, "%s", msg)
+}
+
+func whereNames() []string {
+	names := []string{"true", "false", "now"}
+	for name := range whereFields {
+		names = append(names, name)
+	}
+	for name := range whereFuncs {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// suggest returns the name nearest to s within the edit distance 2, or "".
+func suggest(s string, names []string) string {
+	best, bestDist := "", 3
+	for _, name := range names {
+		if d := editDistance(s, name); d < bestDist {
+			best, bestDist = name, d
+		}
+	}
+	return best
+}
+
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+func (c *whereCompiler) call(node *whereNode) (*whereExpr, error) {
+	fn, ok := whereFuncs[node.text]
+	if !ok {
+		names := make([]string, 0, len(whereFuncs))
+		for name := range whereFuncs {
+			names = append(names, name)
+		}
+		msg := fmt.Sprintf("unknown function %q", node.text)
+		if s := suggest(node.text, names); s != "" {
+			msg += fmt.Sprintf(", did you mean %q?", s)
+		}
+		return nil, c.errorf(node, "%s", msg)
+	}
+	if len(node.args) != len(fn.params) {
+		return nil, c.errorf(node, "%s takes %d arguments, but got %d", node.text, len(fn.params), len(node.args))
+	}
+	args := make([]*whereExpr, len(node.args))
+	isConst := true
+	for i, arg := range node.args {
+		expr, err := c.compile(arg)
+		if err != nil {
+			return nil, err
+		}
+		if expr.typ != fn.params[i] {
+			return nil, c.errorf(arg, "argument %d of %s must be %s, but it is %s", i+1, node.text, fn.params[i], expr.typ)
+		}
+		if slices.Contains(fn.constants, i) && !expr.constant {
+			return nil, c.errorf(arg, "argument %d of %s must be a constant", i+1, node.text)
+		}
+		args[i] = expr
+		isConst = isConst && expr.constant
+	}
+	eval, err := fn.bind(args)
+	if err != nil {
+		return nil, c.errorf(node, "%v", err)
+	}
+	return &whereExpr{typ: fn.result, eval: eval, constant: isConst}, nil
+}
+
+func (c *whereCompiler) list(node *whereNode) (*whereExpr, error) {
+	var (
+		strs []string
+		ints []int64
+		typ  whereType
+	)
+	for i, arg := range node.args {
+		expr, err := c.compile(arg)
+		if err != nil {
+			return nil, err
+		}
+		if !expr.constant {
+			return nil, c.errorf(arg, "elements of list must be constants")
+		}
+		if i == 0 {
+			typ = expr.typ
+		}
+		if expr.typ != typ {
+			return nil, c.errorf(arg, "elements of list must be the same type, but %s and %s are mixed", typ, expr.typ)
+		}
+		v, _ := expr.eval(nil)
+		switch typ {
+		case stringType:
+			strs = append(strs, v.(string))
+		case intType:
+			ints = append(ints, v.(int64))
+		default:
+			return nil, c.errorf(arg, "elements of list must be string or int, but it is %s", typ)
+		}
+	}
+	if typ == intType {
+		return constant(intListType, ints), nil
+	}
+	return constant(stringListType, strs), nil
+}
+
+func (c *whereCompiler) unary(node *whereNode) (*whereExpr, error) {
+	arg, err := c.compile(node.args[0])
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case node.text == "!" && arg.typ == boolType:
+		return &whereExpr{typ: boolType, constant: arg.constant, eval: func(env *whereEnv) (any, error) {
+			v, err := arg.eval(env)
+			if err != nil {
+				return nil, err
+			}
+			return !v.(bool), nil
+		}}, nil
+	case node.text == "-" && (arg.typ == intType || arg.typ == durationType):
+		return &whereExpr{typ: arg.typ, constant: arg.constant, eval: func(env *whereEnv) (any, error) {
+			v, err := arg.eval(env)
+			if err != nil {
+				return nil, err
+			}
+			if d, ok := v.(time.Duration); ok {
+				return -d, nil
+			}
+			return -v.(int64), nil
+		}}, nil
+	}
+	return nil, c.errorf(node, "operator %s is not defined on %s", node.text, arg.typ)
+}
+
+func binaryEval(left, right *whereExpr, op func(a, b any) (any, error)) whereEval {
+	return func(env *whereEnv) (any, error) {
+		a, err := left.eval(env)
+		if err != nil {
+			return nil, err
+		}
+		b, err := right.eval(env)
+		if err != nil {
+			return nil, err
+		}
+		return op(a, b)
+	}
+}
+
+func (c *whereCompiler) binary(node *whereNode) (*whereExpr, error) {
+	left, err := c.compile(node.args[0])
+	if err != nil {
+		return nil, err
+	}
+	right, err := c.compile(node.args[1])
+	if err != nil {
+		return nil, err
+	}
+	isConst := left.constant && right.constant
+	mismatch := func() error {
+		return c.errorf(node, "operator %s is not defined on %s and %s", node.text, left.typ, right.typ)
+	}
+
+	switch node.text {
+	case "&&", "||":
+		if left.typ != boolType || right.typ != boolType {
+			return nil, mismatch()
+		}
+		and := node.text == "&&"
+		return &whereExpr{typ: boolType, constant: isConst, eval: func(env *whereEnv) (any, error) {
+			a, err := left.eval(env)
+			if err != nil {
+				return nil, err
+			}
+			if a.(bool) != and {
+				return a, nil
+			}
+			return right.eval(env)
+		}}, nil
+
+	case "in":
+		var contains func(a, b any) (any, error)
+		switch {
+		case left.typ == stringType && right.typ == stringListType:
+			contains = func(a, b any) (any, error) { return slices.Contains(b.([]string), a.(string)), nil }
+		case left.typ == intType && right.typ == intListType:
+			contains = func(a, b any) (any, error) { return slices.Contains(b.([]int64), a.(int64)), nil }
+		default:
+			return nil, mismatch()
+		}
+		return &whereExpr{typ: boolType, constant: isConst, eval: binaryEval(left, right, contains)}, nil
+
+	case "==", "!=", "<", "<=", ">", ">=":
+		if left.typ != right.typ || left.typ == stringListType || left.typ == intListType ||
+			left.typ == boolType && node.text != "==" && node.text != "!=" {
+			return nil, mismatch()
+		}
+		op := node.text
+		return &whereExpr{typ: boolType, constant: isConst, eval: binaryEval(left, right, func(a, b any) (any, error) {
+			return compareResult(op, compareWhere(a, b)), nil
+		})}, nil
+
+	case "+", "-":
+		sign := int64(1)
+		if node.text == "-" {
+			sign = -1
+		}
+		switch {
+		case left.typ == intType && right.typ == intType:
+			return &whereExpr{typ: intType, constant: isConst, eval: binaryEval(left, right, func(a, b any) (any, error) {
+				return a.(int64) + sign*b.(int64), nil
+			})}, nil
+		case left.typ == durationType && right.typ == durationType:
+			return &whereExpr{typ: durationType, constant: isConst, eval: binaryEval(left, right, func(a, b any) (any, error) {
+				return a.(time.Duration) + time.Duration(sign)*b.(time.Duration), nil
+			})}, nil
+		case left.typ == timeType && right.typ == durationType:
+			return &whereExpr{typ: timeType, constant: isConst, eval: binaryEval(left, right, func(a, b any) (any, error) {
+				return a.(time.Time).Add(time.Duration(sign) * b.(time.Duration)), nil
+			})}, nil
+		case left.typ == timeType && right.typ == timeType && node.text == "-":
+			return &whereExpr{typ: durationType, constant: isConst, eval: binaryEval(left, right, func(a, b any) (any, error) {
+				return a.(time.Time).Sub(b.(time.Time)), nil
+			})}, nil
+		}
+		return nil, mismatch()
+
+	case "&":
+		if left.typ != intType || right.typ != intType {
+			return nil, mismatch()
+		}
+		return &whereExpr{typ: intType, constant: isConst, eval: binaryEval(left, right, func(a, b any) (any, error) {
+			return a.(int64) & b.(int64), nil
+		})}, nil
+	}
+	return nil, c.errorf(node, "unknown operator %s", node.text)
+}
+
+// compareWhere compares values of the same type.
+func compareWhere(a, b any) int {
+	switch a := a.(type) {
+	case int64:
+		return cmpInt(a, b.(int64))
+	case time.Duration:
+		return cmpInt(a, b.(time.Duration))
+	case string:
+		return strings.Compare(a, b.(string))
+	case time.Time:
+		return a.Compare(b.(time.Time))
+	case bool:
+		if a == b.(bool) {
+			return 0
+		}
+		return 1
+	}
+	panic(fmt.Sprintf("uncomparable type %T", a))
+}
+
+func cmpInt[T int64 | time.Duration](a, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func compareResult(op string, cmp int) bool {
+	switch op {
+	case "==":
+		return cmp == 0
+	case "!=":
+		return cmp != 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	}
+	return cmp >= 0
+}
@@ -0,0 +1,28 @@
+package filter
+
+import (
+	"io/fs"
+	"syscall"
+	"time"
+)
+
+func accessTime(info fs.FileInfo) (time.Time, bool) {
+	if st, ok := info.Sys().(*syscall.Stat_t); ok {
+		return time.Unix(st.Atim.Unix()), true
+	}
+	return time.Time{}, false
+}
+
+func changeTime(info fs.FileInfo) (time.Time, bool) {
+	if st, ok := info.Sys().(*syscall.Stat_t); ok {
+		return time.Unix(st.Ctim.Unix()), true
+	}
+	return time.Time{}, false
+}
+
+func fileUID(info fs.FileInfo) (int64, bool) {
+	if st, ok := info.Sys().(*syscall.Stat_t); ok {
+		return int64(st.Uid), true
+	}
+	return 0, false
+}
@@ -0,0 +1,21 @@
+//go:build !linux && !darwin
+
+package filter
+
+import (
+	"io/fs"
+	"time"
+)
+
+// accessTime and changeTime are not supported on this platform.
+func accessTime(fs.FileInfo) (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func changeTime(fs.FileInfo) (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func fileUID(fs.FileInfo) (int64, bool) {
+	return 0, false
+}
@@ -0,0 +1,312 @@
+package filter
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type whereToken int
+
+const (
+	eofToken whereToken = iota
+	identToken
+	numberToken
+	stringToken
+	opToken
+)
+
+type whereLexeme struct {
+	token whereToken
+	text  string
+	// value is the unquoted string of stringToken.
+	value string
+	pos   int
+}
+
+// WhereError is an error of the -where expression at Pos, which is the byte offset in Src.
+type WhereError struct {
+	Src string
+	Pos int
+	Msg string
+}
+
+func (e *WhereError) Error() string {
+	column := utf8.RuneCountInString(e.Src[:e.Pos])
+	return fmt.Sprintf("column %d: %s\n  %s\n  %s^", column+1, e.Msg, e.Src, strings.Repeat(" ", column))
+}
+
+var whereOps = []string{"&&", "||", "==", "!=", "<=", ">=", "!", "<", ">", "+", "-", "&", "(", ")", "[", "]", ","}
+
+func lexWhere(src string) ([]whereLexeme, error) {
+	var lexemes []whereLexeme
+	for i := 0; i < len(src); {
+		r, size := utf8.DecodeRuneInString(src[i:])
+		switch {
+		case unicode.IsSpace(r):
+			i += size
+			continue
+		case r == '"' || r == '\'':
+			value, n, err := unquoteWhere(src[i:])
+			if err != nil {
+				return nil, &WhereError{Src: src, Pos: i, Msg: err.Error()}
+			}
+			lexemes = append(lexemes, whereLexeme{token: stringToken, text: src[i : i+n], value: value, pos: i})
+			i += n
+			continue
+		case r >= '0' && r <= '9':
+			n := i
+			for n < len(src) && (isWhereIdent(src[n]) || src[n] == '.') {
+				n++
+			}
+			lexemes = append(lexemes, whereLexeme{token: numberToken, text: src[i:n], pos: i})
+			i = n
+			continue
+		case r < utf8.RuneSelf && isWhereIdent(byte(r)):
+			n := i
+			for n < len(src) && isWhereIdent(src[n]) {
+				n++
+			}
+			lexemes = append(lexemes, whereLexeme{token: identToken, text: src[i:n], pos: i})
+			i = n
+			continue
+		}
+		op := ""
+		for _, o := range whereOps {
+			if strings.HasPrefix(src[i:], o) {
+				op = o
+				break
+			}
+		}
+		if op == "" {
+			return nil, &WhereError{Src: src, Pos: i, Msg: fmt.Sprintf("unexpected character %q", r)}
+		}
+		lexemes = append(lexemes, whereLexeme{token: opToken, text: op, pos: i})
+		i += len(op)
+	}
+	return append(lexemes, whereLexeme{token: eofToken, pos: len(src)}), nil
+}
+
+func isWhereIdent(c byte) bool {
+	return c == '_' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9'
+}
+
+// unquoteWhere returns the string quoted at the beginning of s and the length of the quoted string.
+// Backslash escapes the next character.
+func unquoteWhere(s string) (string, int, error) {
+	var b strings.Builder
+	quote := s[0]
+	for i := 1; i < len(s); i++ {
+		switch c := s[i]; c {
+		case quote:
+			return b.String(), i + 1, nil
+		case '\\':
+			if i+1 == len(s) {
+				break
+			}
+			i++
+			b.WriteByte(s[i])
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", 0, fmt.Errorf("string is not terminated")
+}
+
+type whereNodeKind int
+
+const (
+	literalNode whereNodeKind = iota
+	stringNode
+	identNode
+	callNode
+	listNode
+	unaryNode
+	binaryNode
+)
+
+type whereNode struct {
+	kind whereNodeKind
+	pos  int
+	// text is the literal, the identifier or the operator.
+	text string
+	args []*whereNode
+}
+
+// precedence of binary operators. Comparisons are not associative.
+const (
+	lowestPrec = iota
+	orPrec
+	andPrec
+	cmpPrec
+	addPrec
+	bitPrec
+)
+
+func infixPrec(l whereLexeme) int {
+	switch {
+	case l.token == opToken:
+		switch l.text {
+		case "||":
+			return orPrec
+		case "&&":
+			return andPrec
+		case "==", "!=", "<", "<=", ">", ">=":
+			return cmpPrec
+		case "+", "-":
+			return addPrec
+		case "&":
+			return bitPrec
+		}
+	case l.token == identToken && l.text == "in":
+		return cmpPrec
+	}
+	return lowestPrec
+}
+
+type whereParser struct {
+	src     string
+	lexemes []whereLexeme
+	i       int
+}
+
+func parseWhere(src string) (*whereNode, error) {
+	lexemes, err := lexWhere(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &whereParser{src: src, lexemes: lexemes}
+	if p.peek().token == eofToken {
+		return nil, p.errorf(p.peek(), "empty expression")
+	}
+	node, err := p.expr(lowestPrec)
+	if err != nil {
+		return nil, err
+	}
+	if l := p.peek(); l.token != eofToken {
+		return nil, p.errorf(l, "unexpected %s", l.describe())
+	}
+	return node, nil
+}
+
+func (p *whereParser) peek() whereLexeme {
+	return p.lexemes[p.i]
+}
+
+func (p *whereParser) next() whereLexeme {
+	l := p.lexemes[p.i]
+	if l.token != eofToken {
+		p.i++
+	}
+	return l
+}
+
+func (p *whereParser) errorf(l whereLexeme, format string, args ...any) error {
+	return &WhereError{Src: p.src, Pos: l.pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (p *whereParser) expect(op string) error {
+	if l := p.next(); l.token != opToken || l.text != op {
+		return p.errorf(l, "expected %q, but got %s", op, l.describe())
+	}
+	return nil
+}
+
+func (l whereLexeme) describe() string {
+	if l.token == eofToken {
+		return "end of expression"
+	}
+	return fmt.Sprintf("%q", l.text)
+}
+
+func (p *whereParser) expr(prec int) (*whereNode, error) {
+	left, err := p.prefix()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op := p.peek()
+		opPrec := infixPrec(op)
+		if opPrec <= prec {
+			return left, nil
+		}
+		p.next()
+		right, err := p.expr(opPrec)
+		if err != nil {
+			return nil, err
+		}
+		left = &whereNode{kind: binaryNode, pos: op.pos, text: op.text, args: []*whereNode{left, right}}
+		if opPrec == cmpPrec && infixPrec(p.peek()) == cmpPrec {
+			return nil, p.errorf(p.peek(), "comparisons can't be chained, use &&")
+		}
+	}
+}
+
+func (p *whereParser) prefix() (*whereNode, error) {
+	l := p.next()
+	switch l.token {
+	case numberToken:
+		return &whereNode{kind: literalNode, pos: l.pos, text: l.text}, nil
+	case stringToken:
+		return &whereNode{kind: stringNode, pos: l.pos, text: l.value}, nil
+	case identToken:
+		if op := p.peek(); op.token == opToken && op.text == "(" {
+			p.next()
+			args, err := p.list(")")
+			if err != nil {
+				return nil, err
+			}
+			return &whereNode{kind: callNode, pos: l.pos, text: l.text, args: args}, nil
+		}
+		return &whereNode{kind: identNode, pos: l.pos, text: l.text}, nil
+	case opToken:
+		switch l.text {
+		case "(":
+			node, err := p.expr(lowestPrec)
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(")"); err != nil {
+				return nil, err
+			}
+			return node, nil
+		case "[":
+			args, err := p.list("]")
+			if err != nil {
+				return nil, err
+			}
+			return &whereNode{kind: listNode, pos: l.pos, args: args}, nil
+		case "!", "-":
+			arg, err := p.expr(bitPrec)
+			if err != nil {
+				return nil, err
+			}
+			return &whereNode{kind: unaryNode, pos: l.pos, text: l.text, args: []*whereNode{arg}}, nil
+		}
+	}
+	return nil, p.errorf(l, "unexpected %s", l.describe())
+}
+
+// list parses expressions separated by commas until the closing op.
+func (p *whereParser) list(closing string) ([]*whereNode, error) {
+	var nodes []*whereNode
+	if l := p.peek(); l.token == opToken && l.text == closing {
+		p.next()
+		return nodes, nil
+	}
+	for {
+		node, err := p.expr(lowestPrec)
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, node)
+		l := p.next()
+		if l.token == opToken && l.text == closing {
+			return nodes, nil
+		}
+		if l.token != opToken || l.text != "," {
+			return nil, p.errorf(l, "expected \",\" or %q, but got %s", closing, l.describe())
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package filter_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/komem3/fing/filter"
+)
+
+type mockDepthEntry struct {
+	*mockDirFileInfo
+	depth int
+}
+
+func (m *mockDepthEntry) Depth() int {
+	return m.depth
+}
+
+func TestWhere_Match(t *testing.T) {
+	now := time.Now()
+	file := &mockDepthEntry{
+		mockDirFileInfo: &mockDirFileInfo{name: "go.mod", typ: 0o644, size: 2 << 20, modTime: now.Add(-time.Hour)},
+		depth:           2,
+	}
+	for _, tt := range []struct {
+		src   string
+		match bool
+	}{
+		{`size > 1M && ext in ["go", "mod"] && mtime > now - 24h`, true},
+		{`size > 1M && ext in ["go", "mod"] && mtime > now - 30m`, false},
+		{`size == 2048k && size < 2.5M`, true},
+		{`size > 1MB && size < 3MB && size == 4096b`, true},
+		{`mtime > now - 1w`, true},
+		{`name == 'go.mod' || name == "main.go"`, true},
+		{`!(name == "go.mod")`, false},
+		{`glob(name, "*.mod") && !matches(path, "^vendor/")`, true},
+		{`contains(lower(path), "GO") || contains(path, "/")`, true},
+		{`depth in [1, 2] && depth + 1 == 3`, true},
+		{`mode & 0o111 != 0 || mode == 0644`, true},
+		{`type == "file" && type != "dir"`, true},
+		{`mtime > date("2000-01-01") && now - mtime < 2h`, true},
+		{`false || (true && 1 > 2)`, false},
+		{`size <= -1 + 1`, false},
+	} {
+		tt := tt
+		t.Run(tt.src, func(t *testing.T) {
+			t.Parallel()
+			where, err := filter.NewWhere(tt.src)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if match, err := where.Match("src/go.mod", file); err != nil || match != tt.match {
+				t.Errorf("Match want %t, but got %t (%v)", tt.match, match, err)
+			}
+		})
+	}
+
+	// the mock has no stat, so fields which need it fail instead of using mtime.
+	for _, src := range []string{`atime > now - 24h`, `ctime > now - 24h`, `uid == 0`} {
+		where, err := filter.NewWhere(src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := where.Match("src/go.mod", file); err == nil {
+			t.Errorf("%s: Match want error", src)
+		}
+	}
+}
+
+func TestNewWhere_error(t *testing.T) {
+	for _, tt := range []struct {
+		src string
+		msg string
+		pos int
+	}{
+		{``, "empty expression", 0},
+		{`sise > 1M`, `unknown field "sise", did you mean "size"?`, 0},
+		{`size > "a"`, "operator > is not defined on int and string", 5},
+		{`size > 1X`, `unknown unit "X" of 1X`, 7},
+		{`name`, "expression must be bool, but it is string", 0},
+		{`name == "a`, "string is not terminated", 8},
+		{`ext in ["go", 1]`, "elements of list must be the same type, but string and int are mixed", 14},
+		{`glob(name, path)`, "argument 2 of glob must be a constant", 11},
+		{`glob(name)`, "glob takes 2 arguments, but got 1", 0},
+		{`matches(name, "(")`, "error parsing regexp", 0},
+		{`1 < size < 2`, "comparisons can't be chained, use &&", 9},
+		{`(size > 1`, `expected ")", but got end of expression`, 9},
+		{`size > 1 #`, `unexpected character '#'`, 9},
+		{`date("yesterday") < mtime`, `"yesterday" is invalid date`, 0},
+		{`now + now > mtime`, "operator + is not defined on time and time", 4},
+	} {
+		tt := tt
+		t.Run(tt.src, func(t *testing.T) {
+			t.Parallel()
+			_, err := filter.NewWhere(tt.src)
+			var whereErr *filter.WhereError
+			if !errors.As(err, &whereErr) {
+				t.Fatalf("want WhereError, but got %v", err)
+			}
+			if !strings.Contains(whereErr.Msg, tt.msg) || whereErr.Pos != tt.pos {
+				t.Errorf("want %q at %d, but got %q at %d", tt.msg, tt.pos, whereErr.Msg, whereErr.Pos)
+			}
+		})
+	}
+}
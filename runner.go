@@ -1,28 +1,107 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log"
+	"os"
+	"os/signal"
 	"strings"
+	"syscall"
 
+	"github.com/komem3/fing/tui"
 	"github.com/komem3/fing/walk"
 )
 
 func run(args []string, stdout, stderr io.Writer) (status int) {
+	args, err := walk.ExpandArgs(args)
+	if err != nil {
+		log.Printf("[ERROR] %v", err)
+		return 1
+	}
 	walker, paths, err := walk.NewWalkerFromArgs(args, stdout, stderr)
 	if err != nil {
 		log.Printf("[ERROR] %v", err)
 		return 1
 	}
+	defer func() {
+		if err := walker.Close(); err != nil {
+			log.Printf("[ERROR] %v", err)
+		}
+	}()
+	if walker.IsTypeList {
+		if err := walker.WriteTypeList(stdout); err != nil {
+			log.Printf("[ERROR] %v", err)
+			return 1
+		}
+		return 0
+	}
+	if walker.IsCompletion {
+		if err := walker.WriteCompletion(stdout); err != nil {
+			log.Printf("[ERROR] %v", err)
+			return 1
+		}
+		return 0
+	}
 	if walker.IsDry {
 		fmt.Fprintf(stdout, "targets=[%s] %s\n", strings.Join(paths, ", "), walker)
 		return 0
 	}
 
-	walker.Walk(paths)
+	if walker.IsInteractive {
+		return runInteractive(walker, paths)
+	}
+	if walker.IsServe {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		if err := walker.Serve(ctx, os.Stdin); err != nil {
+			log.Printf("[ERROR] %v", err)
+			return 1
+		}
+		return 0
+	}
+
+	walker.Run(paths)
+	if walker.IsWatch {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		if err := walker.Watch(ctx); err != nil {
+			log.Printf("[ERROR] %v", err)
+			return 1
+		}
+	}
 	if walker.IsErr {
 		return 1
 	}
 	return 0
 }
+
+func runInteractive(walker *walk.Walker, paths []string) (status int) {
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		log.Printf("[ERROR] %v", err)
+		return 1
+	}
+	defer tty.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	selected, err := tui.Select(ctx, tty, walker.All(ctx, paths))
+	switch {
+	case errors.Is(err, tui.ErrCanceled), errors.Is(err, context.Canceled):
+		// same as fzf
+		return 130
+	case err != nil:
+		log.Printf("[ERROR] %v", err)
+		return 1
+	case len(selected) == 0:
+		return 1
+	}
+	if err := walker.Print(selected); err != nil {
+		log.Printf("[ERROR] %v", err)
+		return 1
+	}
+	return 0
+}
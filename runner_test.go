@@ -40,6 +40,21 @@ var tests = []struct {
 			filepath.FromSlash("testdata/txt_dir/2.txt"),
 		},
 	},
+	{
+		"fing testdata -I -no-hidden -type f",
+		[]string{
+			filepath.FromSlash("testdata/scripts/test.sh"),
+			filepath.FromSlash("testdata/scripts/README.md"),
+			filepath.FromSlash("testdata/txt_dir/1.txt"),
+			filepath.FromSlash("testdata/txt_dir/2.txt"),
+		},
+	},
+	{
+		"fing testdata -no-hidden -hidden -maxdepth 1 -type f -name .*",
+		[]string{
+			filepath.FromSlash("testdata/.gitignore"),
+		},
+	},
 	{
 		"fing testdata -iname *.jpg -regex .*(3|4).*",
 		[]string{
@@ -87,12 +102,101 @@ var tests = []struct {
 			filepath.FromSlash("testdata/scripts"),
 		},
 	},
+	{
+		"fing testdata/txt_dir testdata/scripts -type f -not -T txt",
+		[]string{
+			filepath.FromSlash("testdata/txt_dir/1.txt"),
+			filepath.FromSlash("testdata/txt_dir/2.txt"),
+		},
+	},
+	{
+		"fing testdata -xdev -type f -name *.txt -not -name .*",
+		[]string{
+			filepath.FromSlash("testdata/txt_dir/1.txt"),
+			filepath.FromSlash("testdata/txt_dir/2.txt"),
+		},
+	},
+	{
+		"fing testdata -name *_dir -prune -o -type f -print",
+		[]string{
+			filepath.FromSlash("testdata/.gitignore"),
+			filepath.FromSlash("testdata/.hidden/.hiddne.txt"),
+			filepath.FromSlash("testdata/scripts/README.md"),
+			filepath.FromSlash("testdata/scripts/test.sh"),
+		},
+	},
+	{
+		"fing testdata -name *_dir -prune -o -type f",
+		[]string{
+			filepath.FromSlash("testdata/.gitignore"),
+			filepath.FromSlash("testdata/.hidden/.hiddne.txt"),
+			filepath.FromSlash("testdata/jpg_dir"),
+			filepath.FromSlash("testdata/png_dir"),
+			filepath.FromSlash("testdata/scripts/README.md"),
+			filepath.FromSlash("testdata/scripts/test.sh"),
+			filepath.FromSlash("testdata/txt_dir"),
+		},
+	},
 	{
 		"fing testdata/jpg_dir testdata/png_dir -dry -I -iname txt* -prune -false -o -name *.png -o -not -regex .*\\.name",
 		[]string{
 			"targets=[testdata/jpg_dir, testdata/png_dir] " +
-				filepath.FromSlash("ignore=true prunes=[iname(TXT*)] ") +
-				"condition=[iname(TXT*) && false || name(*.png) || not regex(.*\\.name)]",
+				"ignore=true condition=[iname(TXT*) && prune && false || name(*.png) || not regex(.*\\.name)]",
+		},
+	},
+	{
+		"fing testdata/scripts testdata/txt_dir -type-add shell:*.bash,*.sh -t shell -o -t markdown",
+		[]string{
+			filepath.FromSlash("testdata/scripts/README.md"),
+			filepath.FromSlash("testdata/scripts/test.sh"),
+		},
+	},
+	{
+		"fing testdata/scripts testdata/txt_dir -name *.sh -o -name 1.txt -o -iname readme.*",
+		[]string{
+			filepath.FromSlash("testdata/scripts/README.md"),
+			filepath.FromSlash("testdata/scripts/test.sh"),
+			filepath.FromSlash("testdata/txt_dir/1.txt"),
+		},
+	},
+	{
+		"fing testdata/txt_dir -type f -fuzzy txt -top 2",
+		[]string{
+			filepath.FromSlash("testdata/txt_dir/1.txt"),
+			filepath.FromSlash("testdata/txt_dir/2.txt"),
+		},
+	},
+	{
+		"fing testdata/txt_dir testdata/scripts -sha256 a948904f2f0f479b8f8197694b30184b0d2ed1c1cd2a1ec0fb85d299a192a447",
+		[]string{
+			filepath.FromSlash("testdata/txt_dir/2.txt"),
+		},
+	},
+	{
+		`fing testdata/txt_dir -where depth==1&&ext=="txt"`,
+		[]string{
+			filepath.FromSlash("testdata/txt_dir/1.txt"),
+			filepath.FromSlash("testdata/txt_dir/2.txt"),
+		},
+	},
+	{
+		"fing testdata/txt_dir -T txt",
+		[]string{
+			filepath.FromSlash("testdata/txt_dir"),
+			filepath.FromSlash("testdata/txt_dir/.gitignore"),
+		},
+	},
+	{
+		"fing testdata -contains hello -name *.txt",
+		[]string{
+			filepath.FromSlash("testdata/txt_dir/2.txt"),
+		},
+	},
+	{
+		"fing testdata/scripts testdata/txt_dir -grep ^echo|text$ -grep-print",
+		[]string{
+			filepath.FromSlash("testdata/scripts/test.sh") + ":3:echo \"hello world\"",
+			filepath.FromSlash("testdata/txt_dir/1.txt") + ":1:sample text",
 		},
 	},
 	{
@@ -109,6 +213,11 @@ var tests = []struct {
 }
 
 func TestRun(t *testing.T) {
+	// isolate the default options of the user.
+	home := t.TempDir()
+	t.Setenv("FING_DEFAULT_OPTS", "")
+	t.Setenv("XDG_CONFIG_HOME", home)
+	t.Setenv("HOME", home)
 	for _, tt := range tests {
 		tt := tt
 		t.Run(tt.command, func(t *testing.T) {
@@ -0,0 +1,258 @@
+package tui
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/komem3/fing/filter"
+)
+
+type key int
+
+const (
+	keyRune key = iota
+	keyEnter
+	keyCancel
+	keyBackspace
+	keyClear
+	keyUp
+	keyDown
+	keyPageUp
+	keyPageDown
+	keyToggle
+	keyToggleAll
+)
+
+type keyEvent struct {
+	key key
+	r   rune
+}
+
+type match struct {
+	index int
+	score int
+}
+
+// model is the state of the terminal UI, which is independent of the terminal.
+type model struct {
+	paths    []string
+	query    []rune
+	fuzzy    *filter.Fuzzy
+	matches  []match
+	scored   int
+	selected map[int]bool
+	cursor   int
+	offset   int
+	walking  bool
+	errors   int
+}
+
+func newModel() *model {
+	m := &model{selected: make(map[int]bool), walking: true}
+	m.setQuery(nil)
+	return m
+}
+
+func (m *model) setQuery(query []rune) {
+	m.query = query
+	m.fuzzy = filter.NewFuzzy(string(query))
+	m.matches = m.matches[:0]
+	m.scored = 0
+	m.cursor, m.offset = 0, 0
+	m.update()
+}
+
+func (m *model) add(paths ...string) {
+	m.paths = append(m.paths, paths...)
+	m.update()
+}
+
+// update scores paths which are added after the last update.
+// Matches are sorted by the score while the query is not empty, otherwise they are in the order of arrival.
+func (m *model) update() {
+	if m.scored == len(m.paths) {
+		return
+	}
+	for i := m.scored; i < len(m.paths); i++ {
+		if score, ok := m.fuzzy.Score(m.paths[i]); ok {
+			m.matches = append(m.matches, match{index: i, score: score})
+		}
+	}
+	m.scored = len(m.paths)
+	if len(m.query) > 0 {
+		slices.SortStableFunc(m.matches, func(a, b match) int {
+			if c := cmp.Compare(b.score, a.score); c != 0 {
+				return c
+			}
+			return cmp.Compare(len(m.paths[a.index]), len(m.paths[b.index]))
+		})
+	}
+}
+
+// handle updates the state by the key. It reports whether the selection is finished.
+func (m *model) handle(ev keyEvent, height int) (done bool) {
+	switch ev.key {
+	case keyRune:
+		m.setQuery(append(m.query, ev.r))
+	case keyBackspace:
+		if len(m.query) > 0 {
+			m.setQuery(m.query[:len(m.query)-1])
+		}
+	case keyClear:
+		m.setQuery(nil)
+	case keyUp:
+		m.move(-1, height)
+	case keyDown:
+		m.move(1, height)
+	case keyPageUp:
+		m.move(-max(height, 1), height)
+	case keyPageDown:
+		m.move(max(height, 1), height)
+	case keyToggle:
+		if m.cursor < len(m.matches) {
+			i := m.matches[m.cursor].index
+			if m.selected[i] {
+				delete(m.selected, i)
+			} else {
+				m.selected[i] = true
+			}
+			m.move(1, height)
+		}
+	case keyToggleAll:
+		for _, mt := range m.matches {
+			if m.selected[mt.index] {
+				delete(m.selected, mt.index)
+			} else {
+				m.selected[mt.index] = true
+			}
+		}
+	case keyEnter, keyCancel:
+		return true
+	}
+	return false
+}
+
+func (m *model) move(delta, height int) {
+	m.cursor = max(min(m.cursor+delta, len(m.matches)-1), 0)
+	if m.cursor < m.offset {
+		m.offset = m.cursor
+	}
+	if height > 0 && m.cursor >= m.offset+height {
+		m.offset = m.cursor - height + 1
+	}
+}
+
+// result returns the selected paths in the order of arrival, or the path at the cursor if nothing is selected.
+func (m *model) result() []string {
+	if len(m.selected) > 0 {
+		indexes := make([]int, 0, len(m.selected))
+		for i := range m.selected {
+			indexes = append(indexes, i)
+		}
+		slices.Sort(indexes)
+		paths := make([]string, len(indexes))
+		for i, index := range indexes {
+			paths[i] = m.paths[index]
+		}
+		return paths
+	}
+	if m.cursor < len(m.matches) {
+		return []string{m.paths[m.matches[m.cursor].index]}
+	}
+	return nil
+}
+
+// view returns lines of the screen. The first line is the prompt and the second line is the status.
+func (m *model) view(width, height int) []string {
+	lines := make([]string, 0, height)
+	lines = append(lines, truncate("> "+string(m.query), width))
+
+	status := fmt.Sprintf("  %d/%d", len(m.matches), len(m.paths))
+	if len(m.selected) > 0 {
+		status += fmt.Sprintf(" (%d selected)", len(m.selected))
+	}
+	if m.errors > 0 {
+		status += fmt.Sprintf(" %d errors", m.errors)
+	}
+	if m.walking {
+		status += " ..."
+	}
+	lines = append(lines, truncate(status, width))
+
+	listHeight := height - len(lines)
+	m.move(0, listHeight)
+	for i := m.offset; i < len(m.matches) && len(lines) < height; i++ {
+		var line strings.Builder
+		if i == m.cursor {
+			line.WriteString(">")
+		} else {
+			line.WriteString(" ")
+		}
+		if m.selected[m.matches[i].index] {
+			line.WriteString("*")
+		} else {
+			line.WriteString(" ")
+		}
+		line.WriteString(m.paths[m.matches[i].index])
+		lines = append(lines, truncate(line.String(), width))
+	}
+	return lines
+}
+
+// truncate cuts s to width columns of the terminal.
+// A wide character which crosses the width is removed.
+func truncate(s string, width int) string {
+	if width <= 0 {
+		return s
+	}
+	var w int
+	for i, r := range s {
+		if w += runeWidth(r); w > width {
+			return s[:i]
+		}
+	}
+	return s
+}
+
+// stringWidth returns the number of columns of s in the terminal.
+func stringWidth(s string) int {
+	var w int
+	for _, r := range s {
+		w += runeWidth(r)
+	}
+	return w
+}
+
+type runeRange struct{ lo, hi rune }
+
+// wideRanges are the sorted ranges of East Asian wide and fullwidth characters.
+var wideRanges = []runeRange{
+	{0x1100, 0x115f}, {0x2e80, 0x303e}, {0x3041, 0x33ff}, {0x3400, 0x4dbf}, {0x4e00, 0x9fff},
+	{0xa000, 0xa4cf}, {0xac00, 0xd7a3}, {0xf900, 0xfaff}, {0xfe30, 0xfe4f}, {0xff00, 0xff60},
+	{0xffe0, 0xffe6}, {0x1f300, 0x1f64f}, {0x1f900, 0x1f9ff}, {0x20000, 0x3fffd},
+}
+
+// runeWidth returns the number of columns of r in the terminal.
+func runeWidth(r rune) int {
+	switch {
+	case r < 0x20 || r == 0x7f:
+		return 0
+	case unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf):
+		return 0
+	}
+	if _, found := slices.BinarySearchFunc(wideRanges, r, func(wr runeRange, r rune) int {
+		switch {
+		case wr.hi < r:
+			return -1
+		case wr.lo > r:
+			return 1
+		}
+		return 0
+	}); found {
+		return 2
+	}
+	return 1
+}
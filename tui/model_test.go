@@ -0,0 +1,114 @@
+package tui
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseKeys(t *testing.T) {
+	t.Parallel()
+	got := parseKeys([]byte("aあ\x1b[A\x1b[B\t\x7f\r\x1b[5~\x1bOB\x03"))
+	want := []keyEvent{
+		{key: keyRune, r: 'a'},
+		{key: keyRune, r: 'あ'},
+		{key: keyUp},
+		{key: keyDown},
+		{key: keyToggle},
+		{key: keyBackspace},
+		{key: keyEnter},
+		{key: keyPageUp},
+		{key: keyDown},
+		{key: keyCancel},
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("parseKeys mismatch\nwant: %v\ngot: %v", want, got)
+	}
+	if got := parseKeys([]byte("\x1b")); !reflect.DeepEqual(got, []keyEvent{{key: keyCancel}}) {
+		t.Errorf("escape want cancel, but got %v", got)
+	}
+}
+
+func typeQuery(m *model, query string) {
+	for _, r := range query {
+		m.handle(keyEvent{key: keyRune, r: r}, 10)
+	}
+}
+
+func TestModel(t *testing.T) {
+	t.Parallel()
+	m := newModel()
+	m.add("walk/walk.go", "filter/builder.go")
+	typeQuery(m, "wk")
+	m.add("walk/option.go", "README.md")
+
+	view := m.view(80, 10)
+	want := []string{
+		"> wk",
+		"  2/4 ...",
+		"> walk/walk.go",
+		"  walk/option.go",
+	}
+	if !reflect.DeepEqual(want, view) {
+		t.Errorf("view mismatch\nwant: %q\ngot: %q", want, view)
+	}
+
+	if got := m.result(); !reflect.DeepEqual(got, []string{"walk/walk.go"}) {
+		t.Errorf("result want the path at the cursor, but got %v", got)
+	}
+	m.handle(keyEvent{key: keyDown}, 10)
+	m.handle(keyEvent{key: keyToggle}, 10)
+	m.handle(keyEvent{key: keyUp}, 10)
+	m.handle(keyEvent{key: keyToggle}, 10)
+	if got := m.result(); !reflect.DeepEqual(got, []string{"walk/walk.go", "walk/option.go"}) {
+		t.Errorf("result want selected paths in the order of arrival, but got %v", got)
+	}
+
+	m.handle(keyEvent{key: keyBackspace}, 10)
+	m.handle(keyEvent{key: keyBackspace}, 10)
+	m.walking = false
+	if view := m.view(80, 3); !reflect.DeepEqual(view, []string{"> ", "  4/4 (2 selected)", ">*walk/walk.go"}) {
+		t.Errorf("view after clear: %q", view)
+	}
+	if done := m.handle(keyEvent{key: keyEnter}, 10); !done {
+		t.Errorf("enter want done")
+	}
+}
+
+func TestModel_scroll(t *testing.T) {
+	t.Parallel()
+	m := newModel()
+	m.add("a", "b", "c", "d", "e")
+	m.walking = false
+	for range 3 {
+		m.handle(keyEvent{key: keyDown}, 2)
+	}
+	if view := m.view(5, 4); !reflect.DeepEqual(view, []string{"> ", "  5/5", "  c", "> d"}) {
+		t.Errorf("view after scroll: %q", view)
+	}
+	m.handle(keyEvent{key: keyPageDown}, 2)
+	if m.cursor != 4 {
+		t.Errorf("cursor want 4, but got %d", m.cursor)
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	t.Parallel()
+	for _, tt := range []struct {
+		s     string
+		width int
+		want  string
+	}{
+		{"abcdef", 4, "abcd"},
+		{"abc", 4, "abc"},
+		{"あいう", 4, "あい"},
+		{"aあいう", 4, "aあ"},
+		{"ábc", 2, "áb"},
+	} {
+		if got := truncate(tt.s, tt.width); got != tt.want {
+			t.Errorf("truncate(%q, %d) want %q, but got %q", tt.s, tt.width, tt.want, got)
+		}
+	}
+	if got := stringWidth("> あa"); got != 5 {
+		t.Errorf("stringWidth want 5, but got %d", got)
+	}
+}
@@ -0,0 +1,246 @@
+// Package tui is a terminal UI to select paths found by the walker.
+package tui
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/komem3/fing/walk"
+	"golang.org/x/term"
+)
+
+// ErrCanceled is returned by Select when the user cancels the selection.
+var ErrCanceled = errors.New("canceled")
+
+// refreshInterval limits the frequency of rendering while results are arriving.
+const refreshInterval = 30 * time.Millisecond
+
+// Select shows entries in the terminal as they arrive, and returns paths which the user selects.
+// The user types to filter paths by fuzzy matching, moves with arrow keys, toggles selection with Tab,
+// and finishes with Enter. tty must be a terminal, which is used for both of input and output.
+// Errors of the walk are written to os.Stderr after the terminal is restored.
+func Select(ctx context.Context, tty *os.File, entries iter.Seq2[walk.Entry, error]) ([]string, error) {
+	fd := int(tty.Fd())
+	if !term.IsTerminal(fd) {
+		return nil, errors.New("-interactive needs a terminal")
+	}
+	var walkErrs []error
+	defer func() {
+		for _, err := range walkErrs {
+			fmt.Fprintln(os.Stderr, err)
+		}
+	}()
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return nil, err
+	}
+	defer term.Restore(fd, state)
+	// use the alternate screen to keep the scrollback.
+	if _, err := tty.WriteString("\x1b[?1049h"); err != nil {
+		return nil, err
+	}
+	defer tty.WriteString("\x1b[?1049l")
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type batch struct {
+		paths  []string
+		errors []error
+	}
+	results := make(chan batch)
+	go func() {
+		defer close(results)
+		var (
+			b    batch
+			tick = time.NewTicker(refreshInterval)
+		)
+		defer tick.Stop()
+		send := func() bool {
+			select {
+			case results <- b:
+				b = batch{}
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+		for entry, err := range entries {
+			if err != nil {
+				b.errors = append(b.errors, err)
+			} else {
+				b.paths = append(b.paths, entry.Path)
+			}
+			select {
+			case <-tick.C:
+				if !send() {
+					return
+				}
+			default:
+			}
+		}
+		send()
+	}()
+
+	keys := make(chan keyEvent)
+	go readKeys(ctx, tty, keys)
+
+	m := newModel()
+	tick := time.NewTicker(refreshInterval)
+	defer tick.Stop()
+	var dirty, pending = true, false
+	for {
+		if dirty {
+			if err := render(tty, fd, m); err != nil {
+				return nil, err
+			}
+			dirty = false
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case b, ok := <-results:
+			if !ok {
+				results = nil
+				m.walking = false
+				dirty = true
+			} else {
+				m.errors += len(b.errors)
+				walkErrs = append(walkErrs, b.errors...)
+				m.add(b.paths...)
+				// render many results at once by the tick.
+				pending = true
+			}
+		case <-tick.C:
+			dirty, pending = dirty || pending, false
+		case ev, ok := <-keys:
+			if !ok {
+				return nil, ErrCanceled
+			}
+			_, height, _ := term.GetSize(fd)
+			if m.handle(ev, height-2) {
+				if ev.key == keyCancel {
+					return nil, ErrCanceled
+				}
+				return m.result(), nil
+			}
+			dirty = true
+		}
+	}
+}
+
+func render(tty *os.File, fd int, m *model) error {
+	width, height, err := term.GetSize(fd)
+	if err != nil {
+		return err
+	}
+	var buf strings.Builder
+	buf.WriteString("\x1b[H")
+	for i, line := range m.view(width, height) {
+		if i > 0 {
+			buf.WriteString("\r\n")
+		}
+		if i == 2+m.cursor-m.offset {
+			// reverse the line at the cursor
+			buf.WriteString("\x1b[7m" + line + "\x1b[0m")
+		} else {
+			buf.WriteString(line)
+		}
+		buf.WriteString("\x1b[K")
+	}
+	buf.WriteString("\x1b[J")
+	// move the cursor to the end of the query.
+	buf.WriteString("\x1b[1;" + strconv.Itoa(min(stringWidth("> "+string(m.query))+1, width)) + "H")
+	_, err = tty.WriteString(buf.String())
+	return err
+}
+
+// readKeys reads keys from the terminal until ctx is done or the input is closed.
+// A blocked read remains after Select returns until the next input.
+func readKeys(ctx context.Context, tty *os.File, keys chan<- keyEvent) {
+	defer close(keys)
+	buf := make([]byte, 256)
+	for {
+		n, err := tty.Read(buf)
+		if err != nil {
+			return
+		}
+		for _, ev := range parseKeys(buf[:n]) {
+			select {
+			case keys <- ev:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+var escapeKeys = map[string]key{
+	"\x1b[A":  keyUp,
+	"\x1bOA":  keyUp,
+	"\x1b[B":  keyDown,
+	"\x1bOB":  keyDown,
+	"\x1b[5~": keyPageUp,
+	"\x1b[6~": keyPageDown,
+}
+
+// parseKeys parses the input of the terminal in raw mode.
+func parseKeys(b []byte) []keyEvent {
+	var events []keyEvent
+	for len(b) > 0 {
+		switch c := b[0]; {
+		case c == 0x1b:
+			if len(b) == 1 {
+				return append(events, keyEvent{key: keyCancel})
+			}
+			if b[1] != '[' && b[1] != 'O' {
+				// alt + key
+				b = b[1:]
+				continue
+			}
+			// an escape sequence ends with a letter or '~'.
+			end := bytes.IndexFunc(b[2:], func(r rune) bool {
+				return r == '~' || r >= 'A' && r <= 'Z' || r >= 'a' && r <= 'z'
+			})
+			if end < 0 {
+				return events
+			}
+			if k, ok := escapeKeys[string(b[:end+3])]; ok {
+				events = append(events, keyEvent{key: k})
+			}
+			b = b[end+3:]
+			continue
+		case c == '\r' || c == '\n':
+			events = append(events, keyEvent{key: keyEnter})
+		case c == 0x03 || c == 0x07 || c == 0x04: // Ctrl-C, Ctrl-G, Ctrl-D
+			events = append(events, keyEvent{key: keyCancel})
+		case c == 0x7f || c == 0x08:
+			events = append(events, keyEvent{key: keyBackspace})
+		case c == 0x15: // Ctrl-U
+			events = append(events, keyEvent{key: keyClear})
+		case c == 0x10 || c == 0x0b: // Ctrl-P, Ctrl-K
+			events = append(events, keyEvent{key: keyUp})
+		case c == 0x0e: // Ctrl-N
+			events = append(events, keyEvent{key: keyDown})
+		case c == '\t':
+			events = append(events, keyEvent{key: keyToggle})
+		case c == 0x01: // Ctrl-A
+			events = append(events, keyEvent{key: keyToggleAll})
+		case c >= 0x20:
+			r, size := utf8.DecodeRune(b)
+			events = append(events, keyEvent{key: keyRune, r: r})
+			b = b[size:]
+			continue
+		}
+		b = b[1:]
+	}
+	return events
+}
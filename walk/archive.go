@@ -0,0 +1,420 @@
+package walk
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"time"
+)
+
+// archiveSeparator separates the path of an archive and the path of a member.
+const archiveSeparator = "!/"
+
+type archiveKind int
+
+const (
+	notArchive archiveKind = iota
+	zipArchive
+	tarArchive
+	tgzArchive
+)
+
+func archiveKindOf(name string) archiveKind {
+	name = strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(name, ".zip"), strings.HasSuffix(name, ".jar"):
+		return zipArchive
+	case strings.HasSuffix(name, ".tar"):
+		return tarArchive
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+		return tgzArchive
+	}
+	return notArchive
+}
+
+// archiveFS is a read-only file system of the members of an archive.
+// The member list is read once when the archive is opened, and the archive is reopened to read a member,
+// so that an archive doesn't keep a file descriptor while it's walked.
+// The offsets of members are recorded while listing, so a member is read without scanning the archive again.
+// Members of a compressed tar can't be seeked, so their contents are kept in memory up to maxArchiveMemory.
+type archiveFS struct {
+	kind    archiveKind
+	parent  fs.FS
+	name    string
+	members map[string]*archiveMember
+	*memFS
+}
+
+// archiveMember is the location of a member in the archive, or the content of the member.
+type archiveMember struct {
+	offset, size int64
+	// method is the compression method of a zip member.
+	method   uint16
+	data     []byte
+	inMemory bool
+}
+
+// maxArchiveMemory is the maximum size of the contents kept for a compressed tar.
+const maxArchiveMemory = 16 << 20
+
+var _ fs.ReadDirFS = (*archiveFS)(nil)
+
+func openArchive(parent fs.FS, name string, kind archiveKind) (*archiveFS, error) {
+	a := &archiveFS{kind: kind, parent: parent, name: name, members: make(map[string]*archiveMember), memFS: newMemFS(time.Time{})}
+	a.memFS.open = a.openMember
+
+	r, size, err := a.openSource()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	switch kind {
+	case zipArchive:
+		zr, err := zip.NewReader(r, size)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		for _, f := range zr.File {
+			a.add(f.Name, f.FileInfo())
+			if offset, err := f.DataOffset(); err == nil && (f.Method == zip.Store || f.Method == zip.Deflate) {
+				a.addMember(f.Name, &archiveMember{offset: offset, size: int64(f.CompressedSize64), method: f.Method})
+			}
+		}
+	case tarArchive, tgzArchive:
+		tr, closer, err := a.tarReader(r)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		defer closer.Close()
+		var kept int64
+		for {
+			hdr, err := tr.Next()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", name, err)
+			}
+			a.add(hdr.Name, hdr.FileInfo())
+			if hdr.Typeflag != tar.TypeReg || isSparse(hdr) {
+				continue
+			}
+			switch {
+			case kind == tarArchive:
+				// tar.Reader doesn't read ahead, so the position is the start of the content.
+				offset, err := r.Seek(0, io.SeekCurrent)
+				if err != nil {
+					return nil, fmt.Errorf("%s: %w", name, err)
+				}
+				a.addMember(hdr.Name, &archiveMember{offset: offset, size: hdr.Size})
+			case kept+hdr.Size <= maxArchiveMemory:
+				data, err := io.ReadAll(tr)
+				if err != nil {
+					return nil, fmt.Errorf("%s: %w", name, err)
+				}
+				kept += hdr.Size
+				a.addMember(hdr.Name, &archiveMember{data: data, inMemory: true})
+			}
+		}
+	default:
+		return nil, fmt.Errorf("%s is not an archive", name)
+	}
+	a.sort()
+	return a, nil
+}
+
+// add adds a member with a name in the archive, which may be not clean.
+func (a *archiveFS) add(name string, info fs.FileInfo) {
+	name = cleanMemberName(name)
+	if name == "" {
+		return
+	}
+	a.memFS.add(name, info)
+}
+
+// addMember records the location of a member. The first one is used for duplicated names like add.
+func (a *archiveFS) addMember(name string, m *archiveMember) {
+	name = cleanMemberName(name)
+	if _, ok := a.members[name]; !ok {
+		a.members[name] = m
+	}
+}
+
+func cleanMemberName(name string) string {
+	return path.Clean("/" + strings.ReplaceAll(name, "\\", "/"))[1:]
+}
+
+// isSparse reports whether the content of the member is not stored as is.
+func isSparse(hdr *tar.Header) bool {
+	for key := range hdr.PAXRecords {
+		if strings.HasPrefix(key, "GNU.sparse.") {
+			return true
+		}
+	}
+	return false
+}
+
+type readCloserAt interface {
+	io.ReadSeeker
+	io.ReaderAt
+	io.Closer
+}
+
+// openSource opens the archive file. The returned reader supports io.ReaderAt for zip archives.
+func (a *archiveFS) openSource() (readCloserAt, int64, error) {
+	if a.parent == nil {
+		f, err := os.Open(a.name)
+		if err != nil {
+			return nil, 0, err
+		}
+		info, err := f.Stat()
+		if err != nil {
+			f.Close()
+			return nil, 0, err
+		}
+		return f, info.Size(), nil
+	}
+	buf, err := fs.ReadFile(a.parent, a.name)
+	if err != nil {
+		return nil, 0, err
+	}
+	return nopCloser{bytes.NewReader(buf)}, int64(len(buf)), nil
+}
+
+func (a *archiveFS) tarReader(r io.Reader) (*tar.Reader, io.Closer, error) {
+	if a.kind != tgzArchive {
+		return tar.NewReader(r), io.NopCloser(nil), nil
+	}
+	gr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	return tar.NewReader(gr), gr, nil
+}
+
+func (a *archiveFS) openMember(name string, info fs.FileInfo) (fs.File, error) {
+	m, ok := a.members[name]
+	if !ok {
+		return a.scanMember(name, info)
+	}
+	if m.inMemory {
+		return &memberFile{info: info, Reader: bytes.NewReader(m.data)}, nil
+	}
+	r, _, err := a.openSource()
+	if err != nil {
+		return nil, err
+	}
+	section := io.NewSectionReader(r, m.offset, m.size)
+	if m.method == zip.Deflate {
+		fr := flate.NewReader(section)
+		return &memberFile{info: info, Reader: fr, closers: []io.Closer{fr, r}}, nil
+	}
+	return &memberFile{info: info, Reader: section, closers: []io.Closer{r}}, nil
+}
+
+// scanMember opens a member by scanning the archive, which is used for members whose contents are not recorded.
+func (a *archiveFS) scanMember(name string, info fs.FileInfo) (fs.File, error) {
+	r, size, err := a.openSource()
+	if err != nil {
+		return nil, err
+	}
+
+	switch a.kind {
+	case zipArchive:
+		zr, err := zip.NewReader(r, size)
+		if err != nil {
+			r.Close()
+			return nil, err
+		}
+		for _, f := range zr.File {
+			if cleanMemberName(f.Name) != name {
+				continue
+			}
+			rc, err := f.Open()
+			if err != nil {
+				r.Close()
+				return nil, err
+			}
+			return &memberFile{info: info, Reader: rc, closers: []io.Closer{rc, r}}, nil
+		}
+	case tarArchive, tgzArchive:
+		tr, closer, err := a.tarReader(r)
+		if err != nil {
+			r.Close()
+			return nil, err
+		}
+		for {
+			hdr, err := tr.Next()
+			if err != nil {
+				closer.Close()
+				r.Close()
+				if errors.Is(err, io.EOF) {
+					break
+				}
+				return nil, err
+			}
+			if cleanMemberName(hdr.Name) == name {
+				return &memberFile{info: info, Reader: tr, closers: []io.Closer{closer, r}}, nil
+			}
+		}
+	default:
+		r.Close()
+	}
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+}
+
+type memberFile struct {
+	info fs.FileInfo
+	io.Reader
+	closers []io.Closer
+}
+
+func (m *memberFile) Stat() (fs.FileInfo, error) { return m.info, nil }
+
+func (m *memberFile) Close() error {
+	var errs []error
+	for _, c := range m.closers {
+		errs = append(errs, c.Close())
+	}
+	return errors.Join(errs...)
+}
+
+type nopCloser struct {
+	*bytes.Reader
+}
+
+func (nopCloser) Close() error { return nil }
+
+// memFS is a read-only file system holding directory entries in memory.
+// Content of regular files is read by open.
+type memFS struct {
+	modTime time.Time
+	nodes   map[string]*memNode
+	open    func(name string, info fs.FileInfo) (fs.File, error)
+}
+
+type memNode struct {
+	info     fs.FileInfo
+	children []fs.DirEntry
+}
+
+func newMemFS(modTime time.Time) *memFS {
+	return &memFS{
+		modTime: modTime,
+		nodes:   map[string]*memNode{".": {info: memDirInfo{name: ".", modTime: modTime}}},
+	}
+}
+
+// add adds a file with a clean slash-separated name. Missing parent directories are created.
+func (m *memFS) add(name string, info fs.FileInfo) {
+	if node, ok := m.nodes[name]; ok {
+		// A directory may be created before its own header.
+		if node.info.IsDir() && info.IsDir() {
+			node.info = info
+			p := m.nodes[path.Dir(name)]
+			for i := range p.children {
+				if p.children[i].Name() == info.Name() {
+					p.children[i] = fs.FileInfoToDirEntry(info)
+				}
+			}
+		}
+		return
+	}
+	m.nodes[name] = &memNode{info: info}
+	parent := path.Dir(name)
+	if _, ok := m.nodes[parent]; !ok {
+		m.add(parent, memDirInfo{name: path.Base(parent), modTime: m.modTime})
+	}
+	p := m.nodes[parent]
+	p.children = append(p.children, fs.FileInfoToDirEntry(info))
+}
+
+// sort sorts entries of directories by name. It must be called after all files are added.
+func (m *memFS) sort() {
+	for _, node := range m.nodes {
+		slices.SortFunc(node.children, func(a, b fs.DirEntry) int { return strings.Compare(a.Name(), b.Name()) })
+	}
+}
+
+func (m *memFS) Open(name string) (fs.File, error) {
+	node, ok := m.nodes[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	if node.info.IsDir() {
+		return &memDir{info: node.info, entries: node.children}, nil
+	}
+	if m.open == nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrPermission}
+	}
+	return m.open(name, node.info)
+}
+
+func (m *memFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	node, ok := m.nodes[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+	if !node.info.IsDir() {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: errors.New("not a directory")}
+	}
+	return slices.Clone(node.children), nil
+}
+
+func (m *memFS) Stat(name string) (fs.FileInfo, error) {
+	node, ok := m.nodes[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+	}
+	return node.info, nil
+}
+
+type memDirInfo struct {
+	name    string
+	modTime time.Time
+}
+
+func (d memDirInfo) Name() string       { return d.name }
+func (memDirInfo) Size() int64          { return 0 }
+func (memDirInfo) Mode() fs.FileMode    { return fs.ModeDir | 0o555 }
+func (d memDirInfo) ModTime() time.Time { return d.modTime }
+func (memDirInfo) IsDir() bool          { return true }
+func (memDirInfo) Sys() any             { return nil }
+
+type memDir struct {
+	info    fs.FileInfo
+	entries []fs.DirEntry
+	offset  int
+}
+
+func (d *memDir) Stat() (fs.FileInfo, error) { return d.info, nil }
+func (*memDir) Close() error                 { return nil }
+
+func (d *memDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.info.Name(), Err: errors.New("is a directory")}
+}
+
+func (d *memDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	remain := d.entries[d.offset:]
+	if n <= 0 {
+		d.offset = len(d.entries)
+		return slices.Clone(remain), nil
+	}
+	if len(remain) == 0 {
+		return nil, io.EOF
+	}
+	n = min(n, len(remain))
+	d.offset += n
+	return slices.Clone(remain[:n]), nil
+}
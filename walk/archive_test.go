@@ -0,0 +1,205 @@
+package walk
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"testing/fstest"
+
+	"github.com/komem3/fing/filter"
+)
+
+var archiveFiles = []struct {
+	name string
+	data string
+}{
+	{"config/app.yaml", "name: app"},
+	{"com/example/Main.class", "class"},
+	{"README", "readme"},
+}
+
+func zipData(t *testing.T) []byte {
+	t.Helper()
+	buf := new(bytes.Buffer)
+	zw := zip.NewWriter(buf)
+	for i, f := range archiveFiles {
+		// the first member is stored without compression.
+		method := zip.Deflate
+		if i == 0 {
+			method = zip.Store
+		}
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: f.name, Method: method})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(f.data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func tarData(t *testing.T) []byte {
+	t.Helper()
+	buf := new(bytes.Buffer)
+	tw := tar.NewWriter(buf)
+	if err := tw.WriteHeader(&tar.Header{Name: "./config/", Typeflag: tar.TypeDir, Mode: 0o755}); err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range archiveFiles {
+		if err := tw.WriteHeader(&tar.Header{Name: "./" + f.name, Mode: 0o644, Size: int64(len(f.data))}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(f.data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func tgzData(t *testing.T, data []byte) []byte {
+	t.Helper()
+	buf := new(bytes.Buffer)
+	gw := gzip.NewWriter(buf)
+	if _, err := gw.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := gw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestArchiveFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"release.jar":    {Data: zipData(t)},
+		"release.tar":    {Data: tarData(t)},
+		"release.tar.gz": {Data: tgzData(t, tarData(t))},
+	}
+	for _, tt := range []struct {
+		name string
+		kind archiveKind
+	}{
+		{"release.jar", zipArchive},
+		{"release.tar", tarArchive},
+		{"release.tar.gz", tgzArchive},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if kind := archiveKindOf(tt.name); kind != tt.kind {
+				t.Fatalf("archiveKindOf want %d, but got %d", tt.kind, kind)
+			}
+			afs, err := openArchive(fsys, tt.name, tt.kind)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var files []string
+			for _, f := range archiveFiles {
+				files = append(files, f.name)
+			}
+			if err := fstest.TestFS(afs, files...); err != nil {
+				t.Fatal(err)
+			}
+			for _, f := range archiveFiles {
+				data, err := fs.ReadFile(afs, f.name)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if string(data) != f.data {
+					t.Errorf("%s want %s, but got %s", f.name, f.data, data)
+				}
+				// members are read by the recorded locations instead of scanning the archive.
+				if _, ok := afs.members[f.name]; !ok {
+					t.Errorf("location of %s is not recorded", f.name)
+				}
+			}
+		})
+	}
+}
+
+func TestArchiveFS_large(t *testing.T) {
+	// the content over maxArchiveMemory is not kept, and read by scanning the archive.
+	large := bytes.Repeat([]byte("x"), maxArchiveMemory+1)
+	buf := new(bytes.Buffer)
+	tw := tar.NewWriter(buf)
+	for _, name := range []string{"small", "large"} {
+		data := []byte(name)
+		if name == "large" {
+			data = large
+		}
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(data))}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write(data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	afs, err := openArchive(fstest.MapFS{"large.tgz": {Data: tgzData(t, buf.Bytes())}}, "large.tgz", tgzArchive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := afs.members["large"]; ok {
+		t.Errorf("content of large is kept")
+	}
+	for name, want := range map[string][]byte{"small": []byte("small"), "large": large} {
+		data, err := fs.ReadFile(afs, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(data, want) {
+			t.Errorf("content of %s mismatch", name)
+		}
+	}
+}
+
+func TestWalker_Walk_archives(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "release.zip"), zipData(t), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "release.tgz"), tgzData(t, tarData(t)), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "release.tar"), tarData(t), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := DefaultOptions()
+	opts.Archives = true
+	opts.Matcher = filter.AndExp{mustExp(filter.NewPath("*/config/*")), filter.NewContains("name: app")}
+
+	var paths []string
+	if err := NewWalker(opts).Walk(context.Background(), []string{dir}, func(e Entry) error {
+		paths = append(paths, e.Path)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(paths)
+	want := []string{
+		filepath.Join(dir, "release.tar") + "!/config/app.yaml",
+		filepath.Join(dir, "release.tgz") + "!/config/app.yaml",
+		filepath.Join(dir, "release.zip") + "!/config/app.yaml",
+	}
+	if !reflect.DeepEqual(want, paths) {
+		t.Errorf("Walk mismatch\nwant: %s\ngot: %s", want, paths)
+	}
+}
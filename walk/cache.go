@@ -0,0 +1,178 @@
+package walk
+
+import (
+	"container/list"
+	"io/fs"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/komem3/fing/filter"
+)
+
+// DirCache keeps directory listings and parsed .gitignore files of the OS file system between walks.
+// A directory is read again when its modification time is changed,
+// and an ignore file when its modification time or size is changed.
+// A listing read soon after the modification is not cached,
+// because a file created at the same tick of the clock doesn't change the modification time.
+// The least recently used listings are evicted when the number of cached entries exceeds the limit.
+// A DirCache can be shared by walkers which walk at the same time.
+type DirCache struct {
+	mu sync.Mutex
+	// dirs and ignores are the elements of the lists, which are ordered from the most recently used.
+	dirs       map[string]*list.Element
+	dirList    *list.List
+	entries    int
+	limit      int
+	ignores    map[ignoreKey]*list.Element
+	ignoreList *list.List
+}
+
+type cachedDir struct {
+	name    string
+	modTime time.Time
+	entries []fs.DirEntry
+}
+
+type ignoreKey struct {
+	root, path string
+}
+
+type cachedIgnore struct {
+	key     ignoreKey
+	modTime time.Time
+	size    int64
+	ignore  *filter.Gitignore
+}
+
+const (
+	// defaultCacheEntries is the default limit of entries in cached listings, which is about a few hundred MB.
+	defaultCacheEntries = 1 << 20
+	// maxCachedIgnores is the number of cached ignore files.
+	maxCachedIgnores = 1 << 12
+	// racyWindow is the time after the modification in which a listing may still be changed without changing it.
+	racyWindow = 2 * time.Second
+)
+
+// NewDirCache creates an empty DirCache.
+func NewDirCache() *DirCache {
+	return &DirCache{
+		dirs:       make(map[string]*list.Element),
+		dirList:    list.New(),
+		limit:      defaultCacheEntries,
+		ignores:    make(map[ignoreKey]*list.Element),
+		ignoreList: list.New(),
+	}
+}
+
+// SetLimit sets the maximum number of entries in cached listings.
+func (c *DirCache) SetLimit(entries int) {
+	c.mu.Lock()
+	c.limit = entries
+	c.evict()
+	c.mu.Unlock()
+}
+
+func (c *DirCache) readDir(name string) ([]fs.DirEntry, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		c.forget(name)
+		return nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	if e, ok := c.dirs[name]; ok {
+		if dir := e.Value.(*cachedDir); dir.modTime.Equal(info.ModTime()) {
+			c.dirList.MoveToFront(e)
+			c.mu.Unlock()
+			return dir.entries, nil
+		}
+	}
+	c.mu.Unlock()
+
+	readAt := time.Now()
+	entries, err := f.ReadDir(-1)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	c.remove(name)
+	if readAt.Sub(info.ModTime()) > racyWindow {
+		c.dirs[name] = c.dirList.PushFront(&cachedDir{name: name, modTime: info.ModTime(), entries: entries})
+		c.entries += len(entries)
+		c.evict()
+	}
+	c.mu.Unlock()
+	return entries, nil
+}
+
+func (c *DirCache) forget(name string) {
+	c.mu.Lock()
+	c.remove(name)
+	c.mu.Unlock()
+}
+
+// remove removes the listing of the directory. c.mu must be held.
+func (c *DirCache) remove(name string) {
+	e, ok := c.dirs[name]
+	if !ok {
+		return
+	}
+	c.entries -= len(e.Value.(*cachedDir).entries)
+	c.dirList.Remove(e)
+	delete(c.dirs, name)
+}
+
+// evict removes the least recently used listings over the limit. c.mu must be held.
+func (c *DirCache) evict() {
+	for c.entries > c.limit && c.dirList.Len() > 0 {
+		c.remove(c.dirList.Back().Value.(*cachedDir).name)
+	}
+	for c.ignoreList.Len() > maxCachedIgnores {
+		e := c.ignoreList.Back()
+		c.ignoreList.Remove(e)
+		delete(c.ignores, e.Value.(*cachedIgnore).key)
+	}
+}
+
+func (c *DirCache) gitignore(root, path string) (*filter.Gitignore, error) {
+	info, err := os.Stat(path)
+	key := ignoreKey{root: root, path: path}
+	if err != nil {
+		c.mu.Lock()
+		if e, ok := c.ignores[key]; ok {
+			c.ignoreList.Remove(e)
+			delete(c.ignores, key)
+		}
+		c.mu.Unlock()
+		return nil, err
+	}
+
+	c.mu.Lock()
+	if e, ok := c.ignores[key]; ok {
+		if cached := e.Value.(*cachedIgnore); cached.modTime.Equal(info.ModTime()) && cached.size == info.Size() {
+			c.ignoreList.MoveToFront(e)
+			c.mu.Unlock()
+			return cached.ignore, nil
+		}
+	}
+	c.mu.Unlock()
+
+	ignore, err := filter.NewGitIgnore(root, path)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	if e, ok := c.ignores[key]; ok {
+		c.ignoreList.Remove(e)
+	}
+	c.ignores[key] = c.ignoreList.PushFront(&cachedIgnore{key: key, modTime: info.ModTime(), size: info.Size(), ignore: ignore})
+	c.evict()
+	c.mu.Unlock()
+	return ignore, nil
+}
@@ -0,0 +1,216 @@
+package walk
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/komem3/fing/filter"
+)
+
+// manifest is a list of checksums in the format of sha256sum.
+type manifest struct {
+	algo string
+	sums map[string]string
+}
+
+// readManifest reads the output of sha256sum, md5sum or -checksum.
+// The algorithm is detected by the length of the first checksum.
+func readManifest(name string) (*manifest, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	m := &manifest{sums: make(map[string]string)}
+	scanner := bufio.NewScanner(f)
+	for num := 1; scanner.Scan(); num++ {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if line == "" {
+			continue
+		}
+		escaped := strings.HasPrefix(line, "\\")
+		if escaped {
+			line = line[1:]
+		}
+		sum, path, ok := strings.Cut(line, " ")
+		if !ok || len(path) < 2 || (path[0] != ' ' && path[0] != '*') {
+			return nil, fmt.Errorf("%s:%d: invalid checksum line", name, num)
+		}
+		path = path[1:]
+		if escaped {
+			path = unescapeChecksumPath(path)
+		}
+		algo := "sha256"
+		if len(sum) == 32 {
+			algo = "md5"
+		}
+		if m.algo == "" {
+			m.algo = algo
+		}
+		if algo != m.algo || len(sum) != 32 && len(sum) != 64 {
+			return nil, fmt.Errorf("%s:%d: invalid checksum %s", name, num, sum)
+		}
+		m.sums[filepath.Clean(filepath.FromSlash(path))] = strings.ToLower(sum)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if m.algo == "" {
+		m.algo = "sha256"
+	}
+	return m, nil
+}
+
+// escapeChecksumPath escapes the path in the same way as sha256sum.
+// The line of an escaped path starts with a backslash.
+func escapeChecksumPath(path string) (string, bool) {
+	if !strings.ContainsAny(path, "\\\n\r") {
+		return path, false
+	}
+	return strings.NewReplacer("\\", "\\\\", "\n", "\\n", "\r", "\\r").Replace(path), true
+}
+
+func unescapeChecksumPath(path string) string {
+	return strings.NewReplacer("\\\\", "\\", "\\n", "\n", "\\r", "\r").Replace(path)
+}
+
+func checksumLine(entry *entryInfo, newHash func() hash.Hash) (string, error) {
+	sum, err := filter.Checksum(entry.path, entry, newHash(), 0)
+	if err != nil {
+		return "", err
+	}
+	path, escaped := escapeChecksumPath(entry.path)
+	if escaped {
+		return "\\" + sum + "  " + path + "\n", nil
+	}
+	return sum + "  " + path + "\n", nil
+}
+
+// VerifyResult is a file whose checksum is different from the manifest of -verify.
+type VerifyResult struct {
+	// Status is missing, changed or extra.
+	Status string `json:"status"`
+	Path   string `json:"path"`
+}
+
+// verifyFile compares the checksum of the entry with the manifest.
+func (w *Walker) verifyFile(entry *entryInfo) error {
+	if !entry.Type().IsRegular() {
+		return nil
+	}
+	path := filepath.Clean(entry.path)
+
+	w.writingMutex.Lock()
+	want, ok := w.manifest.sums[path]
+	w.verified[path] = struct{}{}
+	w.writingMutex.Unlock()
+	if !ok {
+		w.writeVerifyResult(VerifyResult{Status: "extra", Path: entry.path})
+		return nil
+	}
+
+	newHash, err := filter.HashFunc(w.manifest.algo)
+	if err != nil {
+		return err
+	}
+	sum, err := filter.Checksum(entry.path, entry, newHash(), 0)
+	if err != nil {
+		w.writeError(entry.path, err)
+		return nil
+	}
+	if sum != want {
+		w.writeVerifyResult(VerifyResult{Status: "changed", Path: entry.path})
+	}
+	return nil
+}
+
+func (w *Walker) runVerify(roots []string) {
+	w.verified = make(map[string]struct{})
+	_ = w.walk(context.Background(), roots, w.verifyFile, w.printError)
+
+	var missing []string
+	for path := range w.manifest.sums {
+		if _, ok := w.verified[path]; !ok && w.isMissing(roots, path) {
+			missing = append(missing, path)
+		}
+	}
+	slices.Sort(missing)
+	for _, path := range missing {
+		w.writeVerifyResult(VerifyResult{Status: "missing", Path: path})
+	}
+}
+
+// isMissing reports whether the file of the manifest, which was not visited, is missing.
+// It is missing if it is under one of the roots, doesn't exist, and may match the expression by the name.
+// Existing files which were not visited are excluded by the expression, -maxdepth, .gitignore and so on.
+func (w *Walker) isMissing(roots []string, path string) bool {
+	if !underRoots(roots, path) {
+		return false
+	}
+	if _, err := os.Lstat(path); !errors.Is(err, fs.ErrNotExist) {
+		return false
+	}
+	if w.matcher == nil {
+		return true
+	}
+	// expressions which need the metadata fail, so that the file is reported.
+	match, err := w.matcher.Match(path, missingEntry{name: filepath.Base(path)})
+	return err != nil || match
+}
+
+func underRoots(roots []string, path string) bool {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return true
+	}
+	for _, root := range roots {
+		root, err := filepath.Abs(root)
+		if err != nil || abs == root || strings.HasPrefix(abs, strings.TrimSuffix(root, string(filepath.Separator))+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// missingEntry is a regular file which doesn't exist anymore.
+type missingEntry struct {
+	name string
+}
+
+var _ fs.DirEntry = missingEntry{}
+
+func (e missingEntry) Name() string { return e.name }
+
+func (missingEntry) IsDir() bool { return false }
+
+func (missingEntry) Type() fs.FileMode { return 0 }
+
+func (missingEntry) Info() (fs.FileInfo, error) { return nil, fs.ErrNotExist }
+
+func (w *Walker) writeVerifyResult(r VerifyResult) {
+	w.writingMutex.Lock()
+	defer w.writingMutex.Unlock()
+	w.IsErr = true
+	var err error
+	if w.json {
+		var b []byte
+		if b, err = json.Marshal(r); err == nil {
+			_, err = fmt.Fprintf(w.out, "%s\n", b)
+		}
+	} else {
+		_, err = fmt.Fprintf(w.out, "%s: %s\n", r.Status, r.Path)
+	}
+	if err != nil {
+		w.printError(err)
+	}
+}
@@ -0,0 +1,97 @@
+package walk
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWalker_Run_checksum(t *testing.T) {
+	dir := t.TempDir()
+	for name, data := range map[string]string{
+		"a.txt": "hello world",
+		"b.txt": "changed",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	run := func(args ...string) (string, bool) {
+		t.Helper()
+		out := new(bytes.Buffer)
+		walker, roots, err := NewWalkerFromArgs(append([]string{"fing", dir, "-name", "*.txt"}, args...), out, out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		walker.Run(roots)
+		return out.String(), walker.IsErr
+	}
+
+	sums, isErr := run("-checksum", "md5")
+	if isErr {
+		t.Fatalf("checksum failed: %s", sums)
+	}
+	if want := "5eb63bbbe01eeed093cb22bb8f5acdc3  " + filepath.Join(dir, "a.txt") + "\n"; !bytes.Contains([]byte(sums), []byte(want)) {
+		t.Errorf("checksum want %s, but got %s", want, sums)
+	}
+	manifest := filepath.Join(t.TempDir(), "MD5SUMS")
+	if err := os.WriteFile(manifest, []byte(sums), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if out, isErr := run("-verify", manifest); isErr || out != "" {
+		t.Errorf("verify want no difference, but got %s", out)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "b.txt"), []byte("CHANGED"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(dir, "a.txt")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "c.txt"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	out, isErr := run("-verify", manifest, "-maxdepth", "1")
+	if !isErr {
+		t.Errorf("verify want error status")
+	}
+	for _, want := range []string{
+		"changed: " + filepath.Join(dir, "b.txt") + "\n",
+		"extra: " + filepath.Join(dir, "c.txt") + "\n",
+		"missing: " + filepath.Join(dir, "a.txt") + "\n",
+	} {
+		if !bytes.Contains([]byte(out), []byte(want)) {
+			t.Errorf("verify output want %s, but got %s", want, out)
+		}
+	}
+
+	// a.txt is outside the root or excluded by the expression, so it is not missing.
+	sub := filepath.Join(dir, "sub")
+	if err := os.Mkdir(sub, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for _, args := range [][]string{
+		{"fing", sub, "-verify", manifest},
+		{"fing", dir, "-name", "b.txt", "-verify", manifest},
+	} {
+		out := new(bytes.Buffer)
+		walker, roots, err := NewWalkerFromArgs(args, out, out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		walker.Run(roots)
+		if bytes.Contains(out.Bytes(), []byte("missing")) {
+			t.Errorf("%v: verify output want no missing file, but got %s", args[1:], out)
+		}
+	}
+}
+
+func TestEscapeChecksumPath(t *testing.T) {
+	for _, path := range []string{"a/b", "a\\b", "a\nb\\n"} {
+		escaped, _ := escapeChecksumPath(path)
+		if got := unescapeChecksumPath(escaped); got != path {
+			t.Errorf("unescape(escape(%q)) = %q", path, got)
+		}
+	}
+}
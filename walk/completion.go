@@ -0,0 +1,179 @@
+package walk
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/komem3/fing/filter"
+)
+
+// completionShells are the shells supported by -completion.
+var completionShells = []string{"bash", "zsh", "fish"}
+
+// WriteCompletion writes the completion script of -completion, which is generated from the options.
+func (w *Walker) WriteCompletion(out io.Writer) error {
+	var s strings.Builder
+	switch w.completionShell {
+	case "bash":
+		writeBashCompletion(&s)
+	case "zsh":
+		writeZshCompletion(&s)
+	case "fish":
+		writeFishCompletion(&s)
+	default:
+		return fmt.Errorf("%s is not supported shell", w.completionShell)
+	}
+	_, err := io.WriteString(out, s.String())
+	return err
+}
+
+// summary returns the first line of the usage.
+func (o *option) summary() string {
+	summary, _, _ := strings.Cut(o.usage, "\n")
+	return summary
+}
+
+func writeBashCompletion(s *strings.Builder) {
+	units := strings.Join(filter.SizeUnits, " ")
+	s.WriteString(`# bash completion for fing, generated by fing -completion bash.
+_fing() {
+	local cur=${COMP_WORDS[COMP_CWORD]} prev=${COMP_WORDS[COMP_CWORD-1]}
+	case $prev in
+`)
+	var noCandidates []string
+	for _, o := range options {
+		if o.flag != nil {
+			continue
+		}
+		if o.complete == completeNone && len(o.values) == 0 {
+			for _, name := range o.names {
+				noCandidates = append(noCandidates, "-"+name)
+			}
+			continue
+		}
+		var replies []string
+		if len(o.values) > 0 {
+			replies = append(replies, fmt.Sprintf(`$(compgen -W '%s' -- "$cur")`, strings.Join(o.values, " ")))
+		}
+		switch o.complete {
+		case completeFile:
+			s.WriteString("\t" + bashPattern(o.names) + ")\n\t\tcompopt -o filenames 2>/dev/null\n")
+			replies = append(replies, `$(compgen -f -- "$cur")`)
+		case completeSize:
+			s.WriteString("\t" + bashPattern(o.names) + ")\n\t\t[[ $cur =~ ^[+-]?[0-9]+$ ]] || return\n")
+			replies = append(replies, fmt.Sprintf(`$(compgen -P "$cur" -W '%s')`, units))
+		default:
+			s.WriteString("\t" + bashPattern(o.names) + ")\n")
+		}
+		fmt.Fprintf(s, "\t\tCOMPREPLY=(%s)\n\t\treturn\n\t\t;;\n", strings.Join(replies, " "))
+	}
+	fmt.Fprintf(s, "\t%s)\n\t\treturn\n\t\t;;\n", strings.Join(noCandidates, "|"))
+
+	var names []string
+	for _, o := range options {
+		names = append(names, bashNames(o.names)...)
+	}
+	fmt.Fprintf(s, `	esac
+
+	if [[ $cur == -* ]]; then
+		COMPREPLY=($(compgen -W '%s' -- "$cur"))
+	else
+		compopt -o filenames 2>/dev/null
+		COMPREPLY=($(compgen -f -- "$cur"))
+	fi
+}
+complete -F _fing fing
+`, strings.Join(names, " "))
+}
+
+func bashNames(names []string) []string {
+	flags := make([]string, len(names))
+	for i, name := range names {
+		flags[i] = "-" + name
+	}
+	return flags
+}
+
+func bashPattern(names []string) string {
+	return strings.Join(bashNames(names), "|")
+}
+
+func writeZshCompletion(s *strings.Builder) {
+	fmt.Fprintf(s, `#compdef fing
+# zsh completion for fing, generated by fing -completion zsh.
+
+_fing_size() {
+	compset -P '(|[+-])<->' && compadd -- %s
+}
+
+_fing() {
+	_arguments \
+`, strings.Join(filter.SizeUnits, " "))
+	for _, o := range options {
+		for _, name := range o.names {
+			fmt.Fprintf(s, "\t\t'*-%s[%s]", name, zshEscape(o.summary()))
+			if o.flag == nil {
+				action := "_files"
+				switch {
+				case o.complete == completeSize:
+					action = "_fing_size"
+				case len(o.values) > 0 && o.complete == completeFile:
+					action = fmt.Sprintf("{_alternative 'values:%s:(%s)' 'files:file:_files'}", o.arg, strings.Join(o.values, " "))
+				case len(o.values) > 0:
+					action = fmt.Sprintf("(%s)", strings.Join(o.values, " "))
+				case o.complete == completeNone:
+					action = " "
+				}
+				fmt.Fprintf(s, ":%s:%s", zshEscape(o.arg), strings.ReplaceAll(action, "'", `'\''`))
+			}
+			s.WriteString("' \\\n")
+		}
+	}
+	s.WriteString(`		'*:starting-point:_files'
+}
+
+if [ "$funcstack[1]" = "_fing" ]; then
+	_fing "$@"
+else
+	compdef _fing fing
+fi
+`)
+}
+
+func zshEscape(str string) string {
+	return strings.NewReplacer(`'`, `'\''`, "[", `\[`, "]", `\]`, ":", `\:`).Replace(str)
+}
+
+func writeFishCompletion(s *strings.Builder) {
+	fmt.Fprintf(s, `# fish completion for fing, generated by fing -completion fish.
+function __fing_size
+	set -l n (commandline -ct)
+	string match -qr '^[+-]?[0-9]+$' -- $n; and printf '%%s\n' $n{%s}
+end
+
+`, strings.Join(filter.SizeUnits, ","))
+	for _, o := range options {
+		for _, name := range o.names {
+			fmt.Fprintf(s, "complete -c fing -o %s", name)
+			if o.flag == nil {
+				switch o.complete {
+				case completeFile:
+					s.WriteString(" -r -F")
+				case completeSize:
+					s.WriteString(" -x -a '(__fing_size)'")
+				default:
+					s.WriteString(" -x")
+				}
+				if len(o.values) > 0 {
+					fmt.Fprintf(s, " -a '%s'", strings.Join(o.values, " "))
+				}
+			}
+			fmt.Fprintf(s, " -d '%s'\n", fishEscape(o.summary()))
+		}
+	}
+}
+
+func fishEscape(str string) string {
+	return strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(str)
+}
@@ -0,0 +1,75 @@
+package walk
+
+import (
+	"bytes"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestWalker_WriteCompletion(t *testing.T) {
+	t.Parallel()
+
+	for _, shell := range completionShells {
+		shell := shell
+		t.Run(shell, func(t *testing.T) {
+			t.Parallel()
+
+			walker, _, err := NewWalkerFromArgs([]string{"fing", "-completion", shell}, new(bytes.Buffer), new(bytes.Buffer))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !walker.IsCompletion {
+				t.Fatal("IsCompletion want true")
+			}
+			out := new(bytes.Buffer)
+			if err := walker.WriteCompletion(out); err != nil {
+				t.Fatal(err)
+			}
+			script := out.String()
+			for _, o := range options {
+				for _, name := range o.names {
+					if !strings.Contains(script, "-"+name) && !strings.Contains(script, "-o "+name+" ") {
+						t.Errorf("%s completion doesn't have -%s", shell, name)
+					}
+				}
+			}
+			if !strings.Contains(script, "f d p s") {
+				t.Errorf("%s completion doesn't have -type letters", shell)
+			}
+
+			if _, err := exec.LookPath(shell); err != nil {
+				t.Skipf("%s is not installed", shell)
+			}
+			cmd := exec.Command(shell, "-n")
+			cmd.Stdin = strings.NewReader(script)
+			if out, err := cmd.CombinedOutput(); err != nil {
+				t.Errorf("%s -n: %v\n%s", shell, err, out)
+			}
+		})
+	}
+}
+
+func TestUsage(t *testing.T) {
+	t.Parallel()
+
+	for _, o := range options {
+		heading := "  -" + strings.Join(o.names, " -")
+		if o.arg != "" {
+			heading += " " + o.arg
+		}
+		if !strings.Contains(Usage, heading+"\n") {
+			t.Errorf("Usage doesn't have %q", heading)
+		}
+		if (o.flag == nil) == (o.value == nil) {
+			t.Errorf("-%s must have either flag or value", o.names[0])
+		}
+		if (o.flag == nil) == (o.arg == "") {
+			t.Errorf("-%s must have arg only if it has value", o.names[0])
+		}
+	}
+
+	if _, _, err := newWalkerFromArgs([]string{"fing", "-completion", "tcsh"}, new(bytes.Buffer), new(bytes.Buffer), false); err == nil {
+		t.Error("-completion tcsh want error")
+	}
+}
@@ -0,0 +1,212 @@
+package walk
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	defaultOptsEnv = "FING_DEFAULT_OPTS"
+	presetPrefix   = "@"
+	maxPresetDepth = 16
+)
+
+// Config is the content of the configuration file.
+//
+// Lines before the first section are default options, which are inserted in front of the command line options.
+// A "[name]" line starts a preset, and "@name" in the command line is replaced by the lines of that section.
+type Config struct {
+	Defaults []string
+	Presets  map[string][]string
+}
+
+// ConfigPath returns $XDG_CONFIG_HOME/fing/config, or ~/.config/fing/config.
+func ConfigPath() (string, error) {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "fing", "config"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".config", "fing", "config"), nil
+}
+
+// LoadConfig reads the configuration file. A missing file is treated as an empty configuration.
+func LoadConfig() (*Config, error) {
+	path, err := ConfigPath()
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return &Config{Presets: map[string][]string{}}, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+	config, err := ParseConfig(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return config, nil
+}
+
+func ParseConfig(r io.Reader) (*Config, error) {
+	config := &Config{Presets: map[string][]string{}}
+	var (
+		scanner = bufio.NewScanner(r)
+		preset  string
+	)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || text[0] == '#' {
+			continue
+		}
+		if text[0] == '[' {
+			if text[len(text)-1] != ']' || len(text) == 2 {
+				return nil, fmt.Errorf("line %d: invalid preset header %s", line, text)
+			}
+			preset = text[1 : len(text)-1]
+			if _, ok := config.Presets[preset]; ok {
+				return nil, fmt.Errorf("line %d: duplicate preset %s", line, preset)
+			}
+			config.Presets[preset] = []string{}
+			continue
+		}
+		args, err := SplitArgs(text)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if preset == "" {
+			config.Defaults = append(config.Defaults, args...)
+		} else {
+			config.Presets[preset] = append(config.Presets[preset], args...)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// ExpandArgs loads the configuration file and FING_DEFAULT_OPTS, and merges them with args.
+func ExpandArgs(args []string) ([]string, error) {
+	config, err := LoadConfig()
+	if err != nil {
+		return nil, err
+	}
+	envOpts, err := SplitArgs(os.Getenv(defaultOptsEnv))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", defaultOptsEnv, err)
+	}
+	return config.Expand(args, envOpts)
+}
+
+// Expand inserts the default options and envOpts after the leading starting-points of args,
+// and replaces presets.
+func (c *Config) Expand(args []string, envOpts []string) ([]string, error) {
+	if len(args) == 0 {
+		return args, nil
+	}
+	n := 1
+	for ; n < len(args); n++ {
+		if args[n] == "" || args[n][0] == '-' || strings.HasPrefix(args[n], presetPrefix) {
+			break
+		}
+	}
+	roots, remain := args[1:n], args[n:]
+	expanded := make([]string, 0, len(args)+len(c.Defaults)+len(envOpts))
+	expanded = append(expanded, args[0])
+	expanded = append(expanded, roots...)
+	expanded = append(expanded, c.Defaults...)
+	expanded = append(expanded, envOpts...)
+	expanded = append(expanded, remain...)
+	return c.expandPresets(expanded[:1], expanded[1:], 0)
+}
+
+func (c *Config) expandPresets(dst, args []string, depth int) ([]string, error) {
+	if depth > maxPresetDepth {
+		return nil, fmt.Errorf("presets are nested too deeply")
+	}
+	for _, arg := range args {
+		if !strings.HasPrefix(arg, presetPrefix) || len(arg) == len(presetPrefix) {
+			dst = append(dst, arg)
+			continue
+		}
+		preset, ok := c.Presets[arg[len(presetPrefix):]]
+		if !ok {
+			return nil, fmt.Errorf("%s is unknown preset", arg)
+		}
+		var err error
+		dst, err = c.expandPresets(dst, preset, depth+1)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return dst, nil
+}
+
+// SplitArgs splits s into arguments like a shell.
+// Single quotes, double quotes and backslash escapes are supported.
+func SplitArgs(s string) ([]string, error) {
+	var (
+		args    []string
+		buf     strings.Builder
+		inArg   bool
+		quote   rune
+		escaped bool
+	)
+	for _, r := range s {
+		switch {
+		case escaped:
+			// In double quotes, a backslash is kept unless it escapes a special character.
+			if quote == '"' && !strings.ContainsRune("\"$`\\", r) {
+				buf.WriteRune('\\')
+			}
+			buf.WriteRune(r)
+			escaped = false
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+			} else {
+				buf.WriteRune(r)
+			}
+		case r == '\\':
+			escaped, inArg = true, true
+		case quote == '"':
+			if r == '"' {
+				quote = 0
+			} else {
+				buf.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote, inArg = r, true
+		case r == ' ' || r == '\t' || r == '\n' || r == '\r':
+			if inArg {
+				args = append(args, buf.String())
+				buf.Reset()
+				inArg = false
+			}
+		default:
+			buf.WriteRune(r)
+			inArg = true
+		}
+	}
+	if escaped {
+		return nil, fmt.Errorf("unterminated backslash in %q", s)
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated quote in %q", s)
+	}
+	if inArg {
+		args = append(args, buf.String())
+	}
+	return args, nil
+}
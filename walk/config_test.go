@@ -0,0 +1,118 @@
+package walk
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplitArgs(t *testing.T) {
+	for _, tt := range []struct {
+		arg  string
+		want []string
+		err  bool
+	}{
+		{"-I  -name *.go", []string{"-I", "-name", "*.go"}, false},
+		{`-name "a b" -path 'c d'`, []string{"-name", "a b", "-path", "c d"}, false},
+		{`-regex "\.go$" -name a\ b`, []string{"-regex", `\.go$`, "-name", "a b"}, false},
+		{`-name ''`, []string{"-name", ""}, false},
+		{"", nil, false},
+		{`-name "a`, nil, true},
+		{`-name a\`, nil, true},
+	} {
+		tt := tt
+		t.Run(tt.arg, func(t *testing.T) {
+			t.Parallel()
+			got, err := SplitArgs(tt.arg)
+			if (err != nil) != tt.err {
+				t.Fatalf("err != nil want %t, but got %v", tt.err, err)
+			}
+			if !reflect.DeepEqual(tt.want, got) {
+				t.Errorf("SplitArgs mismatch\nwant: %q\ngot: %q", tt.want, got)
+			}
+		})
+	}
+}
+
+const testConfig = `
+# default options
+-I -ignore-error
+
+[src]
+-name "*.go" -o -name go.mod
+
+[nosrc]
+-not @src
+`
+
+func TestConfig_Expand(t *testing.T) {
+	config, err := ParseConfig(strings.NewReader(testConfig))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		name    string
+		args    []string
+		envOpts []string
+		want    []string
+		err     bool
+	}{
+		{
+			"insert defaults after roots",
+			[]string{"fing", "a", "b", "-type", "f"},
+			[]string{"-maxdepth", "2"},
+			[]string{"fing", "a", "b", "-I", "-ignore-error", "-maxdepth", "2", "-type", "f"},
+			false,
+		},
+		{
+			"expand preset",
+			[]string{"fing", "@src"},
+			nil,
+			[]string{"fing", "-I", "-ignore-error", "-name", "*.go", "-o", "-name", "go.mod"},
+			false,
+		},
+		{
+			"nested preset",
+			[]string{"fing", ".", "@nosrc"},
+			nil,
+			[]string{"fing", ".", "-I", "-ignore-error", "-not", "-name", "*.go", "-o", "-name", "go.mod"},
+			false,
+		},
+		{
+			"unknown preset",
+			[]string{"fing", "@unknown"},
+			nil,
+			nil,
+			true,
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := config.Expand(tt.args, tt.envOpts)
+			if (err != nil) != tt.err {
+				t.Fatalf("err != nil want %t, but got %v", tt.err, err)
+			}
+			if !reflect.DeepEqual(tt.want, got) {
+				t.Errorf("Expand mismatch\nwant: %q\ngot: %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestParseConfig_Error(t *testing.T) {
+	for _, data := range []string{
+		"[]",
+		"[src\n",
+		"[src]\n[src]\n",
+		"-name 'a\n",
+	} {
+		data := data
+		t.Run(data, func(t *testing.T) {
+			t.Parallel()
+			if _, err := ParseConfig(strings.NewReader(data)); err == nil {
+				t.Error("ParseConfig want error, but got nil")
+			}
+		})
+	}
+}
@@ -0,0 +1,310 @@
+package walk
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"slices"
+	"sync"
+
+	"github.com/komem3/fing/filter"
+)
+
+// partialHashSize is the size of the head hashed before the full content.
+const partialHashSize = 4 * 1024
+
+type fileID struct {
+	dev uint64
+	ino uint64
+}
+
+type dupFile struct {
+	entry *entryInfo
+	size  int64
+	hash  string
+	// links are other entries of the same inode.
+	links []*entryInfo
+}
+
+// DuplicateGroup is a group of files which have the same content.
+// Hardlinks to the same inode are grouped with Type "hardlink", and have no hash.
+type DuplicateGroup struct {
+	Type   string   `json:"type"`
+	Size   int64    `json:"size"`
+	SHA256 string   `json:"sha256,omitempty"`
+	Paths  []string `json:"paths"`
+}
+
+// collectFile collects regular files which are not empty for -duplicates.
+func (w *Walker) collectFile(entry *entryInfo) error {
+	if !entry.Type().IsRegular() {
+		return nil
+	}
+	info, err := entry.Info()
+	if err != nil {
+		w.writeError(entry.path, err)
+		return nil
+	}
+	if info.Size() == 0 {
+		return nil
+	}
+	id, ok := fileIDOf(info)
+
+	w.writingMutex.Lock()
+	defer w.writingMutex.Unlock()
+	f := &dupFile{entry: entry, size: info.Size()}
+	if ok {
+		if first, ok := w.inodes[id]; ok {
+			first.links = append(first.links, entry)
+			return nil
+		}
+		w.inodes[id] = f
+	}
+	w.collected = append(w.collected, f)
+	return nil
+}
+
+func (w *Walker) runDuplicates(roots []string) {
+	w.collected = nil
+	w.inodes = make(map[fileID]*dupFile)
+	_ = w.walk(context.Background(), roots, w.collectFile, w.printError)
+
+	var hardlinks []DuplicateGroup
+	for _, f := range w.collected {
+		if len(f.links) == 0 {
+			continue
+		}
+		// the first path represents the inode so that the output is stable.
+		entries := append([]*entryInfo{f.entry}, f.links...)
+		slices.SortFunc(entries, func(a, b *entryInfo) int { return cmp.Compare(a.path, b.path) })
+		f.entry = entries[0]
+		paths := make([]string, 0, len(entries))
+		for _, e := range entries {
+			paths = append(paths, e.path)
+		}
+		hardlinks = append(hardlinks, DuplicateGroup{Type: "hardlink", Size: f.size, Paths: paths})
+	}
+	groups := append(w.duplicateGroups(), hardlinks...)
+	slices.SortStableFunc(groups, func(a, b DuplicateGroup) int {
+		if a.Type != b.Type {
+			// duplicates first
+			if a.Type == "duplicate" {
+				return -1
+			}
+			return 1
+		}
+		return cmp.Compare(a.Paths[0], b.Paths[0])
+	})
+
+	for i, g := range groups {
+		if err := w.writeDuplicateGroup(i, g); err != nil {
+			log.Printf("[ERROR] %v", err)
+			return
+		}
+	}
+}
+
+// duplicateGroups groups collected files by the size, the partial hash and the full hash in order,
+// so that only files which may be duplicates are read.
+func (w *Walker) duplicateGroups() []DuplicateGroup {
+	bySize := make(map[int64][]*dupFile)
+	for _, f := range w.collected {
+		bySize[f.size] = append(bySize[f.size], f)
+	}
+	var candidates [][]*dupFile
+	for _, files := range bySize {
+		if len(files) > 1 {
+			candidates = append(candidates, files)
+		}
+	}
+
+	candidates = w.splitByHash(candidates, partialHashSize)
+	var full, done [][]*dupFile
+	for _, files := range candidates {
+		if files[0].size <= partialHashSize {
+			done = append(done, files)
+		} else {
+			full = append(full, files)
+		}
+	}
+	done = append(done, w.splitByHash(full, 0)...)
+	if w.dupCompare {
+		done = w.splitByContent(done)
+	}
+
+	groups := make([]DuplicateGroup, 0, len(done))
+	for _, files := range done {
+		paths := make([]string, 0, len(files))
+		for _, f := range files {
+			paths = append(paths, f.entry.path)
+		}
+		slices.Sort(paths)
+		groups = append(groups, DuplicateGroup{Type: "duplicate", Size: files[0].size, SHA256: files[0].hash, Paths: paths})
+	}
+	return groups
+}
+
+// splitByHash hashes files in parallel, and splits each group by the hash.
+// Files which fail to be read are reported and removed.
+func (w *Walker) splitByHash(groups [][]*dupFile, limit int64) [][]*dupFile {
+	var files []*dupFile
+	for _, g := range groups {
+		files = append(files, g...)
+	}
+	ok := make([]bool, len(files))
+	w.parallel(len(files), func(i int) {
+		f := files[i]
+		hash, err := filter.Checksum(f.entry.path, f.entry, sha256.New(), limit)
+		if err != nil {
+			w.writeError(f.entry.path, err)
+			return
+		}
+		f.hash, ok[i] = hash, true
+	})
+
+	var (
+		result [][]*dupFile
+		index  int
+	)
+	for _, g := range groups {
+		byHash := make(map[string][]*dupFile)
+		var order []string
+		for _, f := range g {
+			if ok[index] {
+				if _, exist := byHash[f.hash]; !exist {
+					order = append(order, f.hash)
+				}
+				byHash[f.hash] = append(byHash[f.hash], f)
+			}
+			index++
+		}
+		for _, hash := range order {
+			if len(byHash[hash]) > 1 {
+				result = append(result, byHash[hash])
+			}
+		}
+	}
+	return result
+}
+
+// splitByContent compares files byte by byte with the first file of each subgroup.
+func (w *Walker) splitByContent(groups [][]*dupFile) [][]*dupFile {
+	results := make([][][]*dupFile, len(groups))
+	w.parallel(len(groups), func(i int) {
+		var subgroups [][]*dupFile
+	files:
+		for _, f := range groups[i] {
+			for j, sub := range subgroups {
+				same, err := sameContent(sub[0].entry, f.entry)
+				if err != nil {
+					w.writeError(f.entry.path, err)
+					continue files
+				}
+				if same {
+					subgroups[j] = append(sub, f)
+					continue files
+				}
+			}
+			subgroups = append(subgroups, []*dupFile{f})
+		}
+		for _, sub := range subgroups {
+			if len(sub) > 1 {
+				results[i] = append(results[i], sub)
+			}
+		}
+	})
+	var result [][]*dupFile
+	for _, r := range results {
+		result = append(result, r...)
+	}
+	return result
+}
+
+func sameContent(a, b *entryInfo) (bool, error) {
+	fa, err := filter.OpenFile(a.path, a)
+	if err != nil {
+		return false, err
+	}
+	defer fa.Close()
+	fb, err := filter.OpenFile(b.path, b)
+	if err != nil {
+		return false, err
+	}
+	defer fb.Close()
+
+	bufA, bufB := make([]byte, 32*1024), make([]byte, 32*1024)
+	for {
+		na, errA := io.ReadFull(fa, bufA)
+		nb, errB := io.ReadFull(fb, bufB)
+		if !bytes.Equal(bufA[:na], bufB[:nb]) {
+			return false, nil
+		}
+		endA := errors.Is(errA, io.EOF) || errors.Is(errA, io.ErrUnexpectedEOF)
+		endB := errors.Is(errB, io.EOF) || errors.Is(errB, io.ErrUnexpectedEOF)
+		switch {
+		case errA != nil && !endA:
+			return false, errA
+		case errB != nil && !endB:
+			return false, errB
+		case endA || endB:
+			return endA == endB, nil
+		}
+	}
+}
+
+// parallel calls fn with 0 to n-1 by concurrencyNum goroutines.
+func (w *Walker) parallel(n int, fn func(i int)) {
+	index := make(chan int)
+	var wg sync.WaitGroup
+	for range min(n, concurrencyNum) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range index {
+				fn(i)
+			}
+		}()
+	}
+	for i := range n {
+		index <- i
+	}
+	close(index)
+	wg.Wait()
+}
+
+func (w *Walker) writeDuplicateGroup(i int, g DuplicateGroup) error {
+	if w.json {
+		b, err := json.Marshal(g)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w.out, "%s\n", b)
+		return err
+	}
+	if i > 0 {
+		if _, err := fmt.Fprintln(w.out); err != nil {
+			return err
+		}
+	}
+	if g.Type == "hardlink" {
+		if _, err := fmt.Fprintf(w.out, "# hardlink size=%d\n", g.Size); err != nil {
+			return err
+		}
+	} else {
+		if _, err := fmt.Fprintf(w.out, "# duplicate size=%d sha256=%s\n", g.Size, g.SHA256); err != nil {
+			return err
+		}
+	}
+	for _, path := range g.Paths {
+		if _, err := fmt.Fprintf(w.out, "%s\n", path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
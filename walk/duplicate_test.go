@@ -0,0 +1,68 @@
+package walk
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWalker_Run_duplicates(t *testing.T) {
+	dir := t.TempDir()
+	large := strings.Repeat("x", partialHashSize+1)
+	writeTree(t, dir, "sub/")
+	for name, data := range map[string]string{
+		"a.txt":     "same",
+		"b.txt":     "same",
+		"c.txt":     "diff",
+		"empty1":    "",
+		"empty2":    "",
+		"large1":    large,
+		"large2":    large,
+		"large3":    large[:partialHashSize] + "y",
+		"sub/d.txt": "same",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, filepath.FromSlash(name)), []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	link := os.Link(filepath.Join(dir, "a.txt"), filepath.Join(dir, "e.txt")) == nil
+
+	for _, args := range [][]string{
+		{"fing", dir, "-duplicates"},
+		{"fing", dir, "-duplicates", "-duplicates-compare"},
+	} {
+		out := new(bytes.Buffer)
+		walker, roots, err := NewWalkerFromArgs(args, out, out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		walker.Run(roots)
+
+		want := "# duplicate size=4 sha256=0967115f2813a3541eaef77de9d9d5773f1c0c04314b0bbfe4ff3b3b1c55b5d5\n" +
+			filepath.Join(dir, "a.txt") + "\n" +
+			filepath.Join(dir, "b.txt") + "\n" +
+			filepath.Join(dir, "sub", "d.txt") + "\n" +
+			"\n" +
+			"# duplicate size=4097 sha256=" + mustSHA256(t, large) + "\n" +
+			filepath.Join(dir, "large1") + "\n" +
+			filepath.Join(dir, "large2") + "\n"
+		if link {
+			want += "\n# hardlink size=4\n" +
+				filepath.Join(dir, "a.txt") + "\n" +
+				filepath.Join(dir, "e.txt") + "\n"
+		}
+		if out.String() != want {
+			t.Errorf("%v: output mismatch\nwant:\n%s\ngot:\n%s", args[2:], want, out)
+		}
+	}
+}
+
+func mustSHA256(t *testing.T, s string) string {
+	t.Helper()
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package walk
+
+import "io/fs"
+
+// fileIDOf is not supported on this platform, so hardlinks are reported as duplicates.
+func fileIDOf(fs.FileInfo) (fileID, bool) {
+	return fileID{}, false
+}
@@ -0,0 +1,455 @@
+package walk
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/komem3/fing/filter"
+)
+
+// indexMagic is the header of the index file. The version is updated when the format is changed.
+const indexMagic = "FINGIDX1"
+
+// errNotIndexed is returned when the content of a file in the index is opened.
+var errNotIndexed = errors.New("the content is not in the index")
+
+// index is a list of directories and their entries, which is written by -index build and -snapshot.
+type index struct {
+	roots []string
+	dirs  map[string]*indexDir
+}
+
+type indexDir struct {
+	mode    fs.FileMode
+	modTime time.Time
+	// entries are sorted by name.
+	entries []*indexFile
+}
+
+// indexFile is an entry of the index. It implements both of fs.FileInfo and fs.DirEntry.
+type indexFile struct {
+	name    string
+	mode    fs.FileMode
+	size    int64
+	modTime time.Time
+}
+
+var (
+	_ fs.FileInfo = (*indexFile)(nil)
+	_ fs.DirEntry = (*indexFile)(nil)
+)
+
+func newIndexFile(info fs.FileInfo) *indexFile {
+	return &indexFile{name: info.Name(), mode: info.Mode(), size: info.Size(), modTime: info.ModTime()}
+}
+
+func (f *indexFile) Name() string               { return f.name }
+func (f *indexFile) Size() int64                { return f.size }
+func (f *indexFile) Mode() fs.FileMode          { return f.mode }
+func (f *indexFile) ModTime() time.Time         { return f.modTime }
+func (f *indexFile) IsDir() bool                { return f.mode.IsDir() }
+func (*indexFile) Sys() any                     { return nil }
+func (f *indexFile) Type() fs.FileMode          { return f.mode.Type() }
+func (f *indexFile) Info() (fs.FileInfo, error) { return f, nil }
+
+func newIndex(roots []string) *index {
+	return &index{roots: roots, dirs: make(map[string]*indexDir)}
+}
+
+// DefaultIndexPath returns the path of the index used by -index build without -index-db.
+func DefaultIndexPath() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "fing", "index"), nil
+}
+
+// The format is the magic, the roots and the directories sorted by path.
+// Strings are prefixed with the length, and the path of a directory is front coded,
+// which has the length of the common prefix with the previous path and the rest.
+// Integers are varints.
+func (x *index) write(w io.Writer) error {
+	e := &indexEncoder{w: bufio.NewWriter(w)}
+	e.bytes([]byte(indexMagic))
+	e.uvarint(uint64(len(x.roots)))
+	for _, root := range x.roots {
+		e.string(root)
+	}
+
+	paths := make([]string, 0, len(x.dirs))
+	for path := range x.dirs {
+		paths = append(paths, path)
+	}
+	slices.Sort(paths)
+	e.uvarint(uint64(len(paths)))
+	var prev string
+	for _, path := range paths {
+		common := commonPrefix(prev, path)
+		e.uvarint(uint64(common))
+		e.string(path[common:])
+		prev = path
+
+		dir := x.dirs[path]
+		e.uvarint(uint64(dir.mode))
+		e.varint(dir.modTime.UnixNano())
+		e.uvarint(uint64(len(dir.entries)))
+		for _, f := range dir.entries {
+			e.string(f.name)
+			e.uvarint(uint64(f.mode))
+			e.varint(f.size)
+			e.varint(f.modTime.UnixNano())
+		}
+	}
+	if e.err != nil {
+		return e.err
+	}
+	return e.w.Flush()
+}
+
+func commonPrefix(a, b string) int {
+	n := min(len(a), len(b))
+	for i := 0; i < n; i++ {
+		if a[i] != b[i] {
+			return i
+		}
+	}
+	return n
+}
+
+type indexEncoder struct {
+	w   *bufio.Writer
+	buf [binary.MaxVarintLen64]byte
+	err error
+}
+
+func (e *indexEncoder) bytes(b []byte) {
+	if e.err == nil {
+		_, e.err = e.w.Write(b)
+	}
+}
+
+func (e *indexEncoder) uvarint(v uint64) {
+	e.bytes(e.buf[:binary.PutUvarint(e.buf[:], v)])
+}
+
+func (e *indexEncoder) varint(v int64) {
+	e.bytes(e.buf[:binary.PutVarint(e.buf[:], v)])
+}
+
+func (e *indexEncoder) string(s string) {
+	e.uvarint(uint64(len(s)))
+	if e.err == nil {
+		_, e.err = e.w.WriteString(s)
+	}
+}
+
+func readIndex(r io.Reader) (*index, error) {
+	d := &indexDecoder{r: bufio.NewReader(r)}
+	if magic := d.bytes(len(indexMagic)); d.err == nil && string(magic) != indexMagic {
+		return nil, errors.New("not a fing index")
+	}
+	x := newIndex(nil)
+	for n := d.uvarint(); n > 0 && d.err == nil; n-- {
+		x.roots = append(x.roots, d.string())
+	}
+	var prev string
+	for n := d.uvarint(); n > 0 && d.err == nil; n-- {
+		common := int(d.uvarint())
+		if common > len(prev) {
+			d.err = errors.New("broken path")
+			break
+		}
+		path := prev[:common] + d.string()
+		prev = path
+
+		dir := &indexDir{mode: fs.FileMode(d.uvarint()), modTime: time.Unix(0, d.varint())}
+		count := d.uvarint()
+		dir.entries = make([]*indexFile, 0, min(count, 1024))
+		for ; count > 0 && d.err == nil; count-- {
+			dir.entries = append(dir.entries, &indexFile{
+				name:    d.string(),
+				mode:    fs.FileMode(d.uvarint()),
+				size:    d.varint(),
+				modTime: time.Unix(0, d.varint()),
+			})
+		}
+		x.dirs[path] = dir
+	}
+	if d.err != nil {
+		if errors.Is(d.err, io.EOF) {
+			d.err = io.ErrUnexpectedEOF
+		}
+		return nil, fmt.Errorf("broken index: %w", d.err)
+	}
+	return x, nil
+}
+
+func loadIndex(name string) (*index, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	x, err := readIndex(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return x, nil
+}
+
+// save writes the index to a temporary file and renames it, so that readers never see a partial index.
+func (x *index) save(name string) error {
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(name), filepath.Base(name)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if err := x.write(f); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), name)
+}
+
+type indexDecoder struct {
+	r   *bufio.Reader
+	err error
+}
+
+func (d *indexDecoder) bytes(n int) []byte {
+	if d.err != nil {
+		return nil
+	}
+	b := make([]byte, n)
+	_, d.err = io.ReadFull(d.r, b)
+	return b
+}
+
+func (d *indexDecoder) uvarint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+	v, err := binary.ReadUvarint(d.r)
+	d.err = err
+	return v
+}
+
+func (d *indexDecoder) varint() int64 {
+	if d.err != nil {
+		return 0
+	}
+	v, err := binary.ReadVarint(d.r)
+	d.err = err
+	return v
+}
+
+func (d *indexDecoder) string() string {
+	n := d.uvarint()
+	if n > 1<<20 {
+		d.err = errors.New("too long string")
+	}
+	return string(d.bytes(int(n)))
+}
+
+// indexFS is a file system of the index. Names are absolute paths.
+type indexFS struct {
+	*index
+}
+
+var (
+	_ fs.StatFS    = indexFS{}
+	_ fs.ReadDirFS = indexFS{}
+)
+
+func (x indexFS) clean(name string) string {
+	return filepath.Clean(filepath.FromSlash(name))
+}
+
+func (x indexFS) Stat(name string) (fs.FileInfo, error) {
+	name = x.clean(name)
+	if dir, ok := x.dirs[name]; ok {
+		return &indexFile{name: filepath.Base(name), mode: dir.mode, modTime: dir.modTime}, nil
+	}
+	if parent, ok := x.dirs[filepath.Dir(name)]; ok {
+		base := filepath.Base(name)
+		if i, ok := slices.BinarySearchFunc(parent.entries, base, func(f *indexFile, name string) int {
+			return strings.Compare(f.name, name)
+		}); ok {
+			return parent.entries[i], nil
+		}
+	}
+	return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+}
+
+// ReadDir returns entries of the directory. Directories which were not read by -index build,
+// such as pruned directories, have no entries.
+func (x indexFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	dir, ok := x.dirs[x.clean(name)]
+	if !ok {
+		info, err := x.Stat(name)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			return nil, &fs.PathError{Op: "readdir", Path: name, Err: errors.New("not a directory")}
+		}
+		return nil, nil
+	}
+	entries := make([]fs.DirEntry, len(dir.entries))
+	for i, f := range dir.entries {
+		entries[i] = f
+	}
+	return entries, nil
+}
+
+func (x indexFS) Open(name string) (fs.File, error) {
+	info, err := x.Stat(name)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: errNotIndexed}
+	}
+	entries, err := x.ReadDir(name)
+	if err != nil {
+		return nil, err
+	}
+	return &memDir{info: info, entries: entries}, nil
+}
+
+// refreshFS is the OS file system which records directories read by the walk into the index.
+// The entries of a directory are reused from the previous index if the modification time of the directory is the same.
+type refreshFS struct {
+	prev *index
+
+	mu      sync.Mutex
+	next    *index
+	reused  int
+	entries int
+}
+
+var (
+	_ fs.StatFS    = (*refreshFS)(nil)
+	_ fs.ReadDirFS = (*refreshFS)(nil)
+)
+
+func newRefreshFS(prev *index, roots []string) *refreshFS {
+	return &refreshFS{prev: prev, next: newIndex(roots)}
+}
+
+func (r *refreshFS) Open(name string) (fs.File, error) {
+	return os.Open(name)
+}
+
+func (r *refreshFS) Stat(name string) (fs.FileInfo, error) {
+	return os.Stat(name)
+}
+
+func (r *refreshFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	name = filepath.Clean(filepath.FromSlash(name))
+	info, err := os.Stat(name)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		dir    *indexDir
+		reused bool
+	)
+	if r.prev != nil {
+		if prev, ok := r.prev.dirs[name]; ok && prev.modTime.Equal(info.ModTime()) {
+			dir, reused = prev, true
+		}
+	}
+	if dir == nil {
+		ds, err := os.ReadDir(name)
+		if err != nil {
+			return nil, err
+		}
+		dir = &indexDir{mode: info.Mode(), modTime: info.ModTime(), entries: make([]*indexFile, 0, len(ds))}
+		for _, d := range ds {
+			fi, err := d.Info()
+			if err != nil {
+				// removed after reading the directory
+				continue
+			}
+			dir.entries = append(dir.entries, newIndexFile(fi))
+		}
+	}
+
+	r.mu.Lock()
+	r.next.dirs[name] = dir
+	r.entries += len(dir.entries)
+	if reused {
+		r.reused++
+	}
+	r.mu.Unlock()
+
+	entries := make([]fs.DirEntry, len(dir.entries))
+	for i, f := range dir.entries {
+		entries[i] = f
+	}
+	return entries, nil
+}
+
+// checkIndexQuery returns an error if the options need the file system, which is not in the index.
+func (w *Walker) checkIndexQuery() error {
+	switch {
+	case w.ignoreFile:
+		return errors.New("-I can't be used with -index, because .gitignore is not in the index")
+	case w.archives || w.IsWatch || w.duplicates || w.manifest != nil || w.checksum != nil || w.grepPrint:
+		return errors.New("-archives, -watch, -duplicates, -verify, -checksum and -grep-print can't be used with -index")
+	case filter.ReadsFile(w.matcher) || w.prunes != nil && filter.ReadsFile(w.prunes):
+		return errors.New("expressions which read files, such as -grep, -mime, -sha256 and -git-modified, can't be used with -index")
+	}
+	return nil
+}
+
+// runBuildIndex walks roots and writes the index.
+// Directories which are not modified since the previous index are not read again.
+func (w *Walker) runBuildIndex(roots []string) {
+	for i, root := range roots {
+		abs, err := filepath.Abs(root)
+		if err != nil {
+			w.writeError(root, err)
+			return
+		}
+		roots[i] = abs
+	}
+	prev, err := loadIndex(w.indexDB)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		// rebuild a broken index
+		w.printError(err)
+	}
+
+	refresh := newRefreshFS(prev, roots)
+	w.fsys = refresh
+	_ = w.walk(context.Background(), roots, func(*entryInfo) error { return nil }, w.printError)
+	if err := refresh.next.save(w.indexDB); err != nil {
+		w.writeError(w.indexDB, err)
+		return
+	}
+	if _, err := fmt.Fprintf(w.out, "%s: %d directories (%d unchanged), %d entries\n",
+		w.indexDB, len(refresh.next.dirs), refresh.reused, refresh.entries); err != nil {
+		log.Printf("[ERROR] %v", err)
+	}
+}
@@ -0,0 +1,146 @@
+package walk
+
+import (
+	"bytes"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIndex_write(t *testing.T) {
+	modTime := time.Unix(1700000000, 123)
+	x := newIndex([]string{"/home/user"})
+	x.dirs["/home/user"] = &indexDir{mode: fs.ModeDir | 0o755, modTime: modTime, entries: []*indexFile{
+		{name: "a.txt", mode: 0o644, size: 10, modTime: modTime},
+		{name: "src", mode: fs.ModeDir | 0o755, modTime: modTime},
+	}}
+	x.dirs["/home/user/src"] = &indexDir{mode: fs.ModeDir | 0o755, modTime: modTime, entries: []*indexFile{
+		{name: "link", mode: fs.ModeSymlink | 0o777, size: 4, modTime: modTime},
+	}}
+	x.dirs["/home/user/srv"] = &indexDir{mode: fs.ModeDir | 0o700, modTime: modTime, entries: []*indexFile{}}
+
+	buf := new(bytes.Buffer)
+	if err := x.write(buf); err != nil {
+		t.Fatal(err)
+	}
+	got, err := readIndex(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(x, got) {
+		t.Errorf("readIndex mismatch\nwant: %+v\ngot: %+v", x, got)
+	}
+
+	if _, err := readIndex(bytes.NewReader(buf.Bytes()[:buf.Len()-1])); err == nil {
+		t.Errorf("truncated index want error")
+	}
+	if _, err := readIndex(strings.NewReader("not index")); err == nil {
+		t.Errorf("invalid magic want error")
+	}
+}
+
+func TestWalker_Run_index(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, "a.go", "b.txt", "sub/c.go", "node_modules/d.go")
+	db := filepath.Join(t.TempDir(), "index")
+	run := func(args ...string) string {
+		t.Helper()
+		out := new(bytes.Buffer)
+		walker, roots, err := NewWalkerFromArgs(append([]string{"fing"}, args...), out, out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		walker.Run(roots)
+		if walker.IsErr {
+			t.Fatalf("%v: %s", args, out)
+		}
+		return out.String()
+	}
+
+	build := []string{dir, "-index", "build", "-index-db", db, "-name", "node_modules", "-prune"}
+	if out := run(build...); !strings.Contains(out, "2 directories (0 unchanged)") {
+		t.Errorf("build output: %s", out)
+	}
+	// remove files to make sure that the query doesn't read the file system.
+	if err := os.Remove(filepath.Join(dir, "a.go")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(dir, "sub", "c.go")); err != nil {
+		t.Fatal(err)
+	}
+	want := filepath.Join(dir, "a.go") + "\n" + filepath.Join(dir, "sub", "c.go") + "\n"
+	if out := run("-index", db, "-name", "*.go", "-size", "-1k"); sortLines(out) != want {
+		t.Errorf("query want:\n%s\ngot:\n%s", want, out)
+	}
+	if out := run(filepath.Join(dir, "sub"), "-index", db, "-type", "f"); out != filepath.Join(dir, "sub", "c.go")+"\n" {
+		t.Errorf("query with a root: %s", out)
+	}
+
+	// only the modified directories are read again.
+	run(build...)
+	if out := run(build...); !strings.Contains(out, "2 directories (2 unchanged)") {
+		t.Errorf("refresh output: %s", out)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "sub", "e.go"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if out := run(build...); !strings.Contains(out, "2 directories (1 unchanged)") {
+		t.Errorf("refresh output: %s", out)
+	}
+	want = filepath.Join(dir, "sub", "e.go") + "\n"
+	if out := run(dir, "-index", db, "-name", "*.go"); out != want {
+		t.Errorf("query after refresh want:\n%s\ngot:\n%s", want, out)
+	}
+	// the reused entries don't have the device, but -xdev still reads directories on the same device.
+	if out := run(append(build, "-xdev")...); !strings.Contains(out, "2 directories (2 unchanged)") {
+		t.Errorf("refresh with -xdev output: %s", out)
+	}
+
+	if _, _, err := NewWalkerFromArgs([]string{"fing", "-index", db, "-grep", "go"}, new(bytes.Buffer), new(bytes.Buffer)); err == nil {
+		t.Errorf("-grep with -index want error")
+	}
+}
+
+func TestOnRootDevice_index(t *testing.T) {
+	root, other := t.TempDir(), "/proc"
+	rootInfo, err := os.Stat(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherInfo, err := os.Stat(other)
+	if err != nil {
+		t.Skipf("%s is not found", other)
+	}
+	rootID, ok := fileIDOf(rootInfo)
+	if otherID, _ := fileIDOf(otherInfo); !ok || rootID.dev == otherID.dev {
+		t.Skipf("%s is on the device of %s", other, root)
+	}
+
+	// entries of the index don't have the device, so it is read from the file system.
+	refresh := newRefreshFS(nil, []string{root})
+	dir := &entryInfo{info: newIndexFile(rootInfo), fsys: refresh, name: root}
+	if !onRootDevice(dir) {
+		t.Errorf("root is not on the root device")
+	}
+	sub := &entryInfo{info: newIndexFile(otherInfo), fsys: refresh, name: other, depth: 1, rootDev: dir.rootDev, rootDevOK: dir.rootDevOK}
+	if onRootDevice(sub) {
+		t.Errorf("%s is on the root device", other)
+	}
+
+	// every directory is on the root device if the device of the root is unknown.
+	sub.rootDevOK = false
+	if !onRootDevice(sub) {
+		t.Errorf("%s is not on the unknown root device", other)
+	}
+}
+
+func sortLines(s string) string {
+	lines := strings.SplitAfter(s, "\n")
+	slices.Sort(lines)
+	return strings.Join(lines, "")
+}
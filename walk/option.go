@@ -7,7 +7,9 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"slices"
 	"strconv"
+	"strings"
 
 	"github.com/komem3/fing/filter"
 )
@@ -27,255 +29,924 @@ func (i boolFunc) Set(s string) error {
 	return nil
 }
 
-var Usage = `
-Usage: fing [staring-point...] [flag] [expression]
+// fileTypeExp is a placeholder of -t and -T.
+// It is resolved after parsing arguments, because -type-add may follow them.
+type fileTypeExp struct {
+	name string
+	filter.FileExp
+}
 
-Fing is a fast file finder that provides an interface similar to find.
+func (f *fileTypeExp) String() string {
+	return fmt.Sprint(f.FileExp)
+}
 
-flags are:
-  -dry
-    Only output parse result of expression.
-    If this option is specified, the file will not be searched.
-  -ignore-error
-    Not show errors when opening files, such as permission errors.
-  -maxdepth
-    The depth to search.
-    Unlike find, it can be specified at the same time as prune.
-  -I
-    Ignore files in .gitignore and ~/.fingignore. .fingignore has higher priority.
-
-expression are:
-  -a -and
-    This flag is skipped.
-  -empty
-    Search emptry file and directory.
-    This is shothand of '-size 0c'.
-  -executable
-    Match files which are executable by current user.
-  -false
-    Always false.
-  -iname string
-    Like -name, but the match is case insensitive.
-  -ipath string
-    Like -path, but the match is case insensitive.
-  -iregex string
-    Like -regex, but the match is case insensitive.
-  -irname string
-    Like -rname, but the match is case insensitive.
-  -name string
-    Search for files using glob expressions.
-    This option match only to file name.
-  -not
-    True if next expression false.
-  -o -or
-    Evaluate the previous and next expressions with or.
-  -path string
-    Search for files using wildcard expressions.
-    This option match to file path.
-    Unlike find, This option explicitly matched by using one or more <slash>.
-  -print
-    Add a new line character after the file name. This option is default enabled.
-  -print0
-    Add a null character after the file name.
-  -prune
-    Prunes directory that match before expressions.
-    example: <expression> -prune
-  -regex string
-    Search for files using regular expressions.
-    This option match to file path.
-  -rname string
-    Search for files using regular expressions.
-    This option match only to file name.
-  -size [+|-]n[ckMG]
-    The size of file. Should specify the unit of size.
-    c(for bytes), k(for KiB), M(for MiB), G(for Gib).
-  -true
-    Always true.
-  -type string
-    File is type.
-    Support file(f), directory(d), named piep(p) and socket(s).
-`
+// parser is the state of parsing arguments shared by options.
+type parser struct {
+	walker     *Walker
+	builder    *filter.Builder
+	grepLimit  int64
+	sizes      []*filter.Size
+	sizeRound  bool
+	fileTypes  []*fileTypeExp
+	gitRepos   *filter.GitRepositories
+	indexValue string
+}
 
-func NewWalkerFromArgs(args []string, out, outerr io.Writer) (*Walker, []string, error) {
-	walker := &Walker{
-		out:       bufio.NewWriter(out),
-		outerr:    outerr,
-		depth:     -1,
-		printType: println,
+// completion is how the argument of an option is completed.
+type completion int
+
+const (
+	completeNone completion = iota
+	completeFile
+	completeSize
+)
+
+// option is an option of the command line.
+// The table of options drives the parser, Usage and the completion scripts.
+type option struct {
+	names []string
+	// arg is the argument shown in Usage. Options without flag take an argument.
+	arg   string
+	usage string
+	// expression is true if the option is shown in the expression section of Usage.
+	expression bool
+	// complete and values are candidates of the argument.
+	complete completion
+	values   []string
+
+	flag  func(p *parser, b bool)
+	value func(p *parser, s string) error
+}
+
+// onTrue returns the flag function which calls fn if the flag is true.
+func onTrue(fn func(p *parser)) func(p *parser, b bool) {
+	return func(p *parser, b bool) {
+		if b {
+			fn(p)
+		}
 	}
+}
 
-	flag := flag.NewFlagSet(args[0], flag.ExitOnError)
-	flag.Usage = func() { fmt.Fprint(os.Stderr, Usage) }
+// addExp returns the value function which adds the expression created by newExp.
+func addExp[T filter.FileExp](newExp func(s string) (T, error)) func(p *parser, s string) error {
+	return func(p *parser, s string) error {
+		f, err := newExp(s)
+		if err != nil {
+			return err
+		}
+		p.builder.Add(f)
+		return nil
+	}
+}
 
+// slashed converts the argument of newExp from a slash-separated path.
+func slashed[T filter.FileExp](newExp func(s string) (T, error)) func(s string) (T, error) {
+	return func(s string) (T, error) {
+		return newExp(filepath.FromSlash(s))
+	}
+}
+
+func gitStatus(typ filter.GitStatusType) func(p *parser, b bool) {
+	return onTrue(func(p *parser) {
+		if p.gitRepos == nil {
+			p.gitRepos = filter.NewGitRepositories()
+		}
+		p.builder.Add(filter.NewGitStatus(typ, p.gitRepos))
+	})
+}
+
+var options = []option{
+	// flags
 	{
-		// flags
-		flag.BoolVar(&walker.ignoreFile, "I", false, "")
-		flag.BoolVar(&walker.IsDry, "dry", false, "")
-		flag.Func("maxdepth", "", func(s string) error {
-			d, err := strconv.Atoi(s)
+		names: []string{"archives"},
+		usage: "Search inside zip, jar, tar, tar.gz and tgz files like directories.\n" +
+			"Members of an archive are shown as path/to/a.zip!/inner/file.",
+		flag: func(p *parser, b bool) { p.walker.archives = b },
+	},
+	{
+		names:  []string{"checksum"},
+		arg:    "sha256|md5",
+		usage:  "Print the checksum and the path of matched regular files, like sha256sum and md5sum.",
+		values: []string{"sha256", "md5"},
+		value: func(p *parser, s string) error {
+			newHash, err := filter.HashFunc(s)
 			if err != nil {
 				return err
 			}
-			walker.depth = d
+			p.walker.checksum = newHash
 			return nil
-		})
-	}
-
-	var exp filter.AndExp
-	{
-		// expression
-		var isNot bool
-		_ = flag.Bool("a", false, "")
-		_ = flag.Bool("and", false, "")
-		flag.Var(boolFunc(func(b bool) {
-			if b {
-				f, err := filter.NewSize("0c")
-				if err != nil {
-					panic(err)
-				}
-				exp = append(exp, toFilter(f, &isNot))
-			}
-		}), "empty", "")
-		flag.Var(boolFunc(func(b bool) {
-			if b {
-				exp = append(exp, toFilter(filter.NewExecutable(), &isNot))
+		},
+	},
+	{
+		names:  []string{"completion"},
+		arg:    "bash|zsh|fish",
+		usage:  "Only output the completion script of the shell.\nexample: source <(fing -completion bash)",
+		values: completionShells,
+		value: func(p *parser, s string) error {
+			if !slices.Contains(completionShells, s) {
+				return fmt.Errorf("%s is not supported shell. Support bash, zsh and fish", s)
 			}
-		}), "executable", "")
-		flag.BoolVar(&walker.ignoreErr, "ignore-error", false, "")
-		flag.Func("iname", "", func(s string) error {
-			f, err := filter.NewIFileName(s)
+			p.walker.IsCompletion, p.walker.completionShell = true, s
+			return nil
+		},
+	},
+	{
+		names: []string{"diff"},
+		arg:   "file",
+		usage: "Compare matched entries with the snapshot of -snapshot, and print added(A), removed(D) and changed(M) entries\n" +
+			"with changed metadata, which are size, mode and mtime. Exit with 1 if there is any difference.",
+		complete: completeFile,
+		value: func(p *parser, s string) error {
+			x, err := loadIndex(s)
 			if err != nil {
 				return err
 			}
-			exp = append(exp, toFilter(f, &isNot))
+			p.walker.diffBase = x
 			return nil
-		})
-		flag.Var(boolFunc(func(b bool) {
-			if b {
-				exp = append(exp, toFilter(filter.AlwasyExp(false), &isNot))
-			}
-		}), "false", "")
-		flag.Func("ipath", "", func(s string) error {
-			f, err := filter.NewIPath(filepath.FromSlash(s))
+		},
+	},
+	{
+		names: []string{"dry"},
+		usage: "Only output parse result of expression.\n" +
+			"If this option is specified, the file will not be searched.",
+		flag: func(p *parser, b bool) { p.walker.IsDry = b },
+	},
+	{
+		names: []string{"duplicates"},
+		usage: "Print groups of matched regular files which have the same content instead of the files.\n" +
+			"Files are compared by the size, the sha256 of the first 4KiB and the sha256 of the content in order.\n" +
+			"Empty files are skipped, and hardlinks to the same file are printed as hardlink groups.",
+		flag: func(p *parser, b bool) { p.walker.duplicates = b },
+	},
+	{
+		names: []string{"duplicates-compare"},
+		usage: "Compare the content of -duplicates byte by byte after the hash.",
+		flag:  func(p *parser, b bool) { p.walker.dupCompare = b },
+	},
+	{
+		names: []string{"hidden"},
+		usage: "Search hidden files and directories. This option is default enabled.",
+		flag:  func(p *parser, b bool) { p.walker.skipHidden = !b },
+	},
+	{
+		names: []string{"I"},
+		usage: "Ignore files in .gitignore and ~/.fingignore. .fingignore has higher priority.",
+		flag:  func(p *parser, b bool) { p.walker.ignoreFile = b },
+	},
+	{
+		names: []string{"ignore-error"},
+		usage: "Not show errors when opening files, such as permission errors.",
+		flag:  func(p *parser, b bool) { p.walker.ignoreErr = b },
+	},
+	{
+		names: []string{"index"},
+		arg:   "build|file",
+		usage: "-index build writes an index of entries under the starting-points to the file of -index-db.\n" +
+			"Directories which are not modified since the previous index are not read again,\n" +
+			"so that the metadata of files in them may be old. Prune, -no-hidden and -maxdepth limit directories.\n" +
+			"-index file searches the index instead of the file system. The starting-points are the ones of the index by default,\n" +
+			"and paths are absolute. Expressions which read files, such as -grep and -mime, can't be used.",
+		complete: completeFile,
+		values:   []string{"build"},
+		value: func(p *parser, s string) error {
+			p.indexValue = s
+			return nil
+		},
+	},
+	{
+		names:    []string{"index-db"},
+		arg:      "file",
+		usage:    "The index written by -index build. The default is fing/index in the user cache directory, like ~/.cache/fing/index.",
+		complete: completeFile,
+		value: func(p *parser, s string) error {
+			p.walker.indexDB = s
+			return nil
+		},
+	},
+	{
+		names: []string{"interactive"},
+		usage: "Show matched files in the terminal while searching, and print selected files.\n" +
+			"Type to filter files like -fuzzy, move with Up/Down(Ctrl-P/Ctrl-N), select files with Tab,\n" +
+			"select all files with Ctrl-A, and finish with Enter. Esc and Ctrl-C cancel.",
+		flag: func(p *parser, b bool) { p.walker.IsInteractive = b },
+	},
+	{
+		names: []string{"json"},
+		usage: "Print results as JSON lines. This is supported by -diff, -duplicates, -verify and -watch.",
+		flag:  func(p *parser, b bool) { p.walker.json = b },
+	},
+	{
+		names: []string{"maxdepth"},
+		arg:   "n",
+		usage: "The depth to search.\n" +
+			"Unlike find, it can be specified at the same time as prune.",
+		value: func(p *parser, s string) error {
+			d, err := strconv.Atoi(s)
 			if err != nil {
 				return err
 			}
-			exp = append(exp, toFilter(f, &isNot))
+			p.walker.depth = d
+			return nil
+		},
+	},
+	{
+		names: []string{"no-hidden"},
+		usage: "Skip files and directories whose name starts with a dot, like fd.\n" +
+			"Hidden directories are not read. Starting-points are never skipped.",
+		flag: func(p *parser, b bool) { p.walker.skipHidden = b },
+	},
+	{
+		names: []string{"serve"},
+		usage: "Serve searches with JSON-RPC 2.0 over stdin and stdout, which has a request or a response per line.\n" +
+			`The search method takes {"args": [...]}, which are the starting-points and expressions like the command line,` + "\n" +
+			`and sends matched paths with result notifications {"id": id, "paths": [...]} before the response {"count": n}.` + "\n" +
+			`The cancel method takes {"id": id} and cancels the search. Directory listings and .gitignore files are cached` + "\n" +
+			"between searches, and directories which are not modified are not read again.",
+		flag: func(p *parser, b bool) { p.walker.IsServe = b },
+	},
+	{
+		names:    []string{"serve-socket"},
+		arg:      "path",
+		usage:    "Like -serve, but serve connections to the Unix socket of the path.",
+		complete: completeFile,
+		value: func(p *parser, s string) error {
+			p.walker.IsServe, p.walker.serveSocket = true, s
+			return nil
+		},
+	},
+	{
+		names: []string{"size-round"},
+		usage: "Round file sizes up to the unit of -size like find.\n" +
+			"For example, -size 1M matches files from 1 byte to 1MiB, and -size -1M matches only empty files.",
+		flag: func(p *parser, b bool) { p.sizeRound = b },
+	},
+	{
+		names:    []string{"snapshot"},
+		arg:      "file",
+		usage:    "Save matched entries with the size, the mode and the mtime to the file for -diff.",
+		complete: completeFile,
+		value: func(p *parser, s string) error {
+			p.walker.snapshotFile = s
 			return nil
-		})
-		flag.Func("iregex", "", func(s string) error {
-			f, err := filter.NewIRegex(filepath.FromSlash(s))
+		},
+	},
+	{
+		names: []string{"top"},
+		arg:   "n",
+		usage: "Print only the n best-ranked files of -fuzzy.",
+		value: func(p *parser, s string) error {
+			n, err := strconv.Atoi(s)
 			if err != nil {
 				return err
 			}
-			exp = append(exp, toFilter(f, &isNot))
+			if n <= 0 {
+				return fmt.Errorf("-top must be positive: %d", n)
+			}
+			p.walker.top = n
 			return nil
-		})
-		flag.Func("irname", "", func(s string) error {
-			f, err := filter.NewIRegexName(s)
+		},
+	},
+	{
+		names: []string{"verify"},
+		arg:   "manifest",
+		usage: "Compare matched regular files with the manifest, which is the output of -checksum or sha256sum.\n" +
+			"Print changed files, extra files which are not in the manifest, and missing files which are not found,\n" +
+			"and exit with 1 if there is any difference. Files of the manifest are missing only if they are under\n" +
+			"the starting-points, don't exist, and may match the expression by the name.",
+		complete: completeFile,
+		value: func(p *parser, s string) error {
+			m, err := readManifest(s)
 			if err != nil {
 				return err
 			}
-			exp = append(exp, toFilter(f, &isNot))
+			p.walker.manifest = m
+			return nil
+		},
+	},
+	{
+		names: []string{"watch"},
+		usage: "After searching, watch searched directories and print files which are created, modified\n" +
+			"or moved into a matching state until interrupted. Created directories are also watched.\n" +
+			"This uses inotify and is supported only on Linux.",
+		flag: func(p *parser, b bool) { p.walker.IsWatch = b },
+	},
+	{
+		names: []string{"xdev", "mount"},
+		usage: "Don't descend into directories on other file systems than the starting-point, like find.\n" +
+			"Mount points themselves are still matched.",
+		flag: func(p *parser, b bool) { p.walker.xdev = b },
+	},
+
+	// expressions
+	{
+		names:      []string{"a", "and"},
+		usage:      "This flag is skipped.",
+		expression: true,
+		flag:       func(*parser, bool) {},
+	},
+	{
+		names: []string{"contains"},
+		arg:   "string",
+		usage: "Match regular files whose content contains the string.\n" +
+			"Binary files, which have a null byte in the first 8000 bytes, never match.\n" +
+			"This is evaluated after other expressions joined by and.",
+		expression: true,
+		value: func(p *parser, s string) error {
+			f := filter.NewContains(s)
+			p.walker.contents = append(p.walker.contents, f)
+			p.builder.Add(f)
 			return nil
-		})
-		flag.Func("name", "", func(s string) error {
-			f, err := filter.NewFileName(s)
+		},
+	},
+	{
+		names:      []string{"empty"},
+		usage:      "Match empty regular files and directories which have no entries.",
+		expression: true,
+		flag:       onTrue(func(p *parser) { p.builder.Add(filter.NewEmpty()) }),
+	},
+	{
+		names:      []string{"executable"},
+		usage:      "Match files which are executable by current user.",
+		expression: true,
+		flag:       onTrue(func(p *parser) { p.builder.Add(filter.NewExecutable()) }),
+	},
+	{
+		names:      []string{"false"},
+		usage:      "Always false.",
+		expression: true,
+		flag:       onTrue(func(p *parser) { p.builder.Add(filter.AlwasyExp(false)) }),
+	},
+	{
+		names: []string{"filter-cmd"},
+		arg:   "command",
+		usage: "Match files for which the command exits with 0. {} in the command is replaced by the path,\n" +
+			"and the path is appended if there is no {}. The command is split like a shell, but not run by a shell.\n" +
+			"This is evaluated after other expressions joined by and.\n" +
+			"example: -filter-cmd 'grep -q Copyright {}'",
+		expression: true,
+		value: func(p *parser, s string) error {
+			args, err := SplitArgs(s)
 			if err != nil {
 				return err
 			}
-			exp = append(exp, toFilter(f, &isNot))
-			return nil
-		})
-		flag.BoolVar(&isNot, "not", false, "")
-		orFunc := func(b bool) {
-			if b {
-				if len(exp) > 0 {
-					walker.matcher = append(walker.matcher, exp)
-					exp = filter.AndExp{}
-				}
-			}
-		}
-		flag.Var(boolFunc(orFunc), "o", "")
-		flag.Var(boolFunc(orFunc), "or", "")
-		flag.Func("path", "", func(s string) error {
-			f, err := filter.NewPath(filepath.FromSlash(s))
+			f, err := filter.NewCommand(args)
 			if err != nil {
 				return err
 			}
-			exp = append(exp, toFilter(f, &isNot))
+			p.walker.commands = append(p.walker.commands, f)
+			p.builder.Add(f)
 			return nil
-		})
-		flag.Var(boolFunc(func(b bool) {
-			if b {
-				walker.printType = println
+		},
+	},
+	{
+		names: []string{"filter-coproc"},
+		arg:   "command",
+		usage: "Like -filter-cmd, but the command is started once, and paths are written to its stdin line by line.\n" +
+			"The command must answer a line of yes or no (y, true, 1, n, false, 0) for each path in order.",
+		expression: true,
+		value: func(p *parser, s string) error {
+			args, err := SplitArgs(s)
+			if err != nil {
+				return err
 			}
-		}), "print", "")
-		flag.Var(boolFunc(func(b bool) {
-			if b {
-				walker.printType = print0
+			f, err := filter.NewCoprocess(args)
+			if err != nil {
+				return err
 			}
-		}), "print0", "")
-		flag.Var(boolFunc(func(b bool) {
-			if b {
-				walker.prunes = append(walker.prunes, exp)
+			p.walker.closers = append(p.walker.closers, f)
+			p.walker.commands = append(p.walker.commands, f)
+			p.builder.Add(f)
+			return nil
+		},
+	},
+	{
+		names: []string{"fstype"},
+		arg:   "type",
+		usage: "Match files on a file system of the type, such as ext4, tmpfs, nfs and overlay.\n" +
+			"The type is read from /proc/self/mountinfo, so this is supported only on Linux.\n" +
+			"example: -fstype proc -prune -o -print",
+		expression: true,
+		value:      addExp(filter.NewFSType),
+	},
+	{
+		names: []string{"fuzzy"},
+		arg:   "string",
+		usage: "Match paths which contain the characters of the string in order, like fzf.\n" +
+			"Matched files are printed in the order of the score after searching.\n" +
+			"Consecutive characters and the beginning of words and path elements get a higher score.\n" +
+			"The match is case insensitive unless the string has an upper case letter.",
+		expression: true,
+		value: func(p *parser, s string) error {
+			if p.walker.fuzzy != nil {
+				return fmt.Errorf("-fuzzy can be specified only once")
 			}
-		}), "prune", "")
-		flag.Func("regex", "", func(s string) error {
-			f, err := filter.NewRegex(filepath.FromSlash(s))
+			p.walker.fuzzy = filter.NewFuzzy(s)
+			p.builder.Add(p.walker.fuzzy)
+			return nil
+		},
+	},
+	{
+		names:      []string{"git-ignored"},
+		usage:      "Match files ignored by the git repository which contains them.",
+		expression: true,
+		flag:       gitStatus(filter.GitIgnored),
+	},
+	{
+		names:      []string{"git-modified"},
+		usage:      "Match files modified in the working tree of the git repository.",
+		expression: true,
+		flag:       gitStatus(filter.GitModified),
+	},
+	{
+		names:      []string{"git-staged"},
+		usage:      "Match files which have changes staged in the git index.",
+		expression: true,
+		flag:       gitStatus(filter.GitStaged),
+	},
+	{
+		names:      []string{"git-untracked"},
+		usage:      "Match files not tracked by the git repository.",
+		expression: true,
+		flag:       gitStatus(filter.GitUntracked),
+	},
+	{
+		names:      []string{"grep"},
+		arg:        "regex",
+		usage:      "Like -contains, but match lines of the content using regular expressions.",
+		expression: true,
+		value: func(p *parser, s string) error {
+			f, err := filter.NewGrep(s)
 			if err != nil {
 				return err
 			}
-			exp = append(exp, toFilter(f, &isNot))
+			p.walker.contents = append(p.walker.contents, f)
+			p.builder.Add(f)
 			return nil
-		})
-		flag.Func("rname", "", func(s string) error {
-			f, err := filter.NewRegexName(s)
+		},
+	},
+	{
+		names:      []string{"grep-limit"},
+		arg:        "size",
+		usage:      "Read up to size bytes of each file for -contains and -grep. The unit is same as -size, but it can't be omitted.",
+		expression: true,
+		complete:   completeSize,
+		value: func(p *parser, s string) error {
+			limit, err := filter.ParseSize(s)
 			if err != nil {
 				return err
 			}
-			exp = append(exp, toFilter(f, &isNot))
+			p.grepLimit = limit
 			return nil
-		})
-		flag.Func("size", "", func(s string) error {
+		},
+	},
+	{
+		names:      []string{"grep-print"},
+		usage:      "Print matched lines of -contains and -grep as path:line:text instead of the file name.",
+		expression: true,
+		flag:       func(p *parser, b bool) { p.walker.grepPrint = b },
+	},
+	{
+		names:      []string{"iname"},
+		arg:        "string",
+		usage:      "Like -name, but the match is case insensitive.",
+		expression: true,
+		value:      addExp(filter.NewIFileName),
+	},
+	{
+		names:      []string{"ipath"},
+		arg:        "string",
+		usage:      "Like -path, but the match is case insensitive.",
+		expression: true,
+		value:      addExp(slashed(filter.NewIPath)),
+	},
+	{
+		names:      []string{"iregex"},
+		arg:        "string",
+		usage:      "Like -regex, but the match is case insensitive.",
+		expression: true,
+		value:      addExp(slashed(filter.NewIRegex)),
+	},
+	{
+		names:      []string{"irname"},
+		arg:        "string",
+		usage:      "Like -rname, but the match is case insensitive.",
+		expression: true,
+		value:      addExp(filter.NewIRegexName),
+	},
+	{
+		names: []string{"kind"},
+		arg:   "string",
+		usage: "Match files whose content is the kind, which is detected by the first bytes like -mime.\n" +
+			"Support image, video, audio, archive, text and binary. binary matches all files which are not text.",
+		expression: true,
+		values: []string{
+			string(filter.ImageKind), string(filter.VideoKind), string(filter.AudioKind),
+			string(filter.ArchiveKind), string(filter.TextKind), string(filter.BinaryKind),
+		},
+		value: addExp(filter.NewKind),
+	},
+	{
+		names:      []string{"md5"},
+		arg:        "string",
+		usage:      "Match regular files whose md5 is the hex string.",
+		expression: true,
+		value:      addExp(filter.NewMD5),
+	},
+	{
+		names: []string{"mime"},
+		arg:   "string",
+		usage: "Match files whose media type detected by the first bytes matches the glob pattern.\n" +
+			"example: -mime 'image/*'",
+		expression: true,
+		value:      addExp(filter.NewMime),
+	},
+	{
+		names: []string{"name"},
+		arg:   "string",
+		usage: "Search for files using glob expressions.\n" +
+			"This option match only to file name.\n" +
+			"Successive names joined by -o are matched at once, like -name '*.jpg' -o -name '*.png'.",
+		expression: true,
+		value:      addExp(filter.NewFileName),
+	},
+	{
+		names: []string{"name-from"},
+		arg:   "file",
+		usage: "Like -name, but match any of glob patterns in the file, which has a pattern per line.\n" +
+			"Empty lines and lines starting with # are skipped.",
+		expression: true,
+		complete:   completeFile,
+		value:      addExp(filter.NewNameSetFromFile),
+	},
+	{
+		names:      []string{"not"},
+		usage:      "True if next expression false.",
+		expression: true,
+		flag:       onTrue(func(p *parser) { p.builder.Not() }),
+	},
+	{
+		names:      []string{"o", "or"},
+		usage:      "Evaluate the previous and next expressions with or.",
+		expression: true,
+		flag:       onTrue(func(p *parser) { p.builder.Or() }),
+	},
+	{
+		names: []string{"path"},
+		arg:   "string",
+		usage: "Search for files using wildcard expressions.\n" +
+			"This option match to file path.\n" +
+			"Unlike find, This option explicitly matched by using one or more <slash>.",
+		expression: true,
+		value:      addExp(slashed(filter.NewPath)),
+	},
+	{
+		names: []string{"print"},
+		usage: "Print the file name followed by a new line character. This is always true.\n" +
+			"Like find, if the expression has neither -print nor -print0, files which match the whole expression are printed.",
+		expression: true,
+		flag: onTrue(func(p *parser) {
+			p.walker.printType = println
+			p.builder.Print()
+		}),
+	},
+	{
+		names:      []string{"print0"},
+		usage:      "Print the file name followed by a null character. This is always true.",
+		expression: true,
+		flag: onTrue(func(p *parser) {
+			p.walker.printType = print0
+			p.builder.Print0()
+		}),
+	},
+	{
+		names: []string{"prune"},
+		usage: "Don't descend into the directory. This is always true.\n" +
+			"example: <expression> -prune -o <expression>",
+		expression: true,
+		flag:       onTrue(func(p *parser) { p.builder.Prune() }),
+	},
+	{
+		names: []string{"regex"},
+		arg:   "string",
+		usage: "Search for files using regular expressions.\n" +
+			"This option match to file path.",
+		expression: true,
+		value:      addExp(slashed(filter.NewRegex)),
+	},
+	{
+		names: []string{"rname"},
+		arg:   "string",
+		usage: "Search for files using regular expressions.\n" +
+			"This option match only to file name.",
+		expression: true,
+		value:      addExp(filter.NewRegexName),
+	},
+	{
+		names:      []string{"sha256"},
+		arg:        "string",
+		usage:      "Match regular files whose sha256 is the hex string.",
+		expression: true,
+		value:      addExp(filter.NewSHA256),
+	},
+	{
+		names: []string{"size"},
+		arg:   "[+|-]n[unit]",
+		usage: "The size of file. The unit is b(for 512-byte blocks, which is the default), c(for bytes), w(for 2-byte words),\n" +
+			"k, M, G, T, P(for KiB, MiB, GiB, TiB, PiB) or kB, MB, GB, TB, PB(for 1000, 1000^2, ... bytes).\n" +
+			"A range such as 10M..1G matches sizes between them inclusive, and either side can be omitted.\n" +
+			"Unlike find, sizes are not rounded up to the unit without -size-round.",
+		expression: true,
+		complete:   completeSize,
+		value: func(p *parser, s string) error {
 			f, err := filter.NewSize(s)
 			if err != nil {
 				return err
 			}
-			exp = append(exp, toFilter(f, &isNot))
+			p.sizes = append(p.sizes, f)
+			p.builder.Add(f)
+			return nil
+		},
+	},
+	{
+		names: []string{"t"},
+		arg:   "string",
+		usage: "Match files of the file type, such as go, js, proto, markdown, docker and make.\n" +
+			"A file type is a set of glob patterns matched to the file name. See -type-list.",
+		expression: true,
+		values:     filter.DefaultFileTypes().Names(),
+		value: func(p *parser, s string) error {
+			f := &fileTypeExp{name: s}
+			p.fileTypes = append(p.fileTypes, f)
+			p.builder.Add(f)
 			return nil
-		})
-		flag.Var(boolFunc(func(b bool) {
-			if b {
-				exp = append(exp, toFilter(filter.AlwasyExp(true), &isNot))
+		},
+	},
+	{
+		names:      []string{"T"},
+		arg:        "string",
+		usage:      "Like -t, but match files which are not the file type.",
+		expression: true,
+		values:     filter.DefaultFileTypes().Names(),
+		value: func(p *parser, s string) error {
+			f := &fileTypeExp{name: s}
+			p.fileTypes = append(p.fileTypes, f)
+			p.builder.Add(filter.NewNotExp(f))
+			return nil
+		},
+	},
+	{
+		names:      []string{"true"},
+		usage:      "Always true.",
+		expression: true,
+		flag:       onTrue(func(p *parser) { p.builder.Add(filter.AlwasyExp(true)) }),
+	},
+	{
+		names: []string{"type"},
+		arg:   "string",
+		usage: "File is type.\n" +
+			"Support file(f), directory(d), named piep(p) and socket(s).",
+		expression: true,
+		values:     []string{"f", "d", "p", "s"},
+		value:      addExp(filter.NewFileType),
+	},
+	{
+		names: []string{"type-add"},
+		arg:   "name:glob[,glob]",
+		usage: "Add glob patterns to the file type. A new file type is created if it doesn't exist.\n" +
+			"example: -type-add 'web:*.html,*.css' -t web",
+		expression: true,
+		value: func(p *parser, s string) error {
+			return p.walker.fileTypes.Add(s)
+		},
+	},
+	{
+		names:      []string{"type-list"},
+		usage:      "Only output file types and their glob patterns.",
+		expression: true,
+		flag:       func(p *parser, b bool) { p.walker.IsTypeList = b },
+	},
+	{
+		names: []string{"where"},
+		arg:   "expression",
+		usage: "Match files for which the expression is true. The expression is type checked before searching.\n" +
+			"Fields are name, path, ext, type, size, mode, mtime, atime, ctime, uid and depth.\n" +
+			"Operators are ||, &&, !, ==, !=, <, <=, >, >=, in, +, - and &. Strings are quoted by \" or ',\n" +
+			"sizes have a unit such as 10k and 1M, durations are such as 30m, 24h and 7d, and now is the current time.\n" +
+			"Functions are glob(s, pattern), matches(s, regex), contains(s, sub), lower(s) and date(\"2006-01-02\").\n" +
+			"example: -where 'size > 1M && ext in [\"go\", \"mod\"] && mtime > now - 24h'",
+		expression: true,
+		value:      addExp(filter.NewWhere),
+	},
+}
+
+var Usage = usage()
+
+func usage() string {
+	var s strings.Builder
+	s.WriteString(`
+Usage: fing [staring-point...] [flag] [expression]
+
+Fing is a fast file finder that provides an interface similar to find.
+`)
+	for _, section := range []struct {
+		title      string
+		expression bool
+	}{{"flags are:", false}, {"expression are:", true}} {
+		fmt.Fprintf(&s, "\n%s\n", section.title)
+		for _, o := range options {
+			if o.expression != section.expression {
+				continue
 			}
-		}), "true", "")
-		flag.Func("type", "", func(s string) error {
-			f, err := filter.NewFileType(s)
-			if err != nil {
-				return err
+			s.WriteString(" ")
+			for _, name := range o.names {
+				s.WriteString(" -" + name)
 			}
-			exp = append(exp, toFilter(f, &isNot))
-			return nil
-		})
+			if o.arg != "" {
+				s.WriteString(" " + o.arg)
+			}
+			s.WriteString("\n")
+			for _, line := range strings.Split(o.usage, "\n") {
+				s.WriteString("    " + line + "\n")
+			}
+		}
+	}
+	s.WriteString(`
+configuration:
+  Default options are read from $XDG_CONFIG_HOME/fing/config (~/.config/fing/config)
+  and the FING_DEFAULT_OPTS environment variable, and inserted after the starting-points.
+  A "[name]" line in the configuration file starts a preset, and "@name" is replaced by it.
+`)
+	return s.String()
+}
+
+func NewWalkerFromArgs(args []string, out, outerr io.Writer) (*Walker, []string, error) {
+	return newWalkerFromArgs(args, out, outerr, true)
+}
+
+// newWalkerFromArgs is NewWalkerFromArgs which returns errors of flags instead of exiting if exitOnError is false.
+func newWalkerFromArgs(args []string, out, outerr io.Writer, exitOnError bool) (*Walker, []string, error) {
+	walker := &Walker{
+		out:       bufio.NewWriter(out),
+		outerr:    outerr,
+		depth:     -1,
+		printType: println,
+		fileTypes: filter.DefaultFileTypes(),
+	}
+	p := &parser{walker: walker, builder: filter.NewBuilder()}
+
+	errorHandling := flag.ExitOnError
+	if !exitOnError {
+		errorHandling = flag.ContinueOnError
+	}
+	flag := flag.NewFlagSet(args[0], errorHandling)
+	flag.Usage = func() { fmt.Fprint(os.Stderr, Usage) }
+	if !exitOnError {
+		flag.SetOutput(io.Discard)
+		flag.Usage = func() {}
+	}
+	for _, o := range options {
+		for _, name := range o.names {
+			if o.flag != nil {
+				flag.Var(boolFunc(func(b bool) { o.flag(p, b) }), name, "")
+			} else {
+				flag.Func(name, "", func(s string) error { return o.value(p, s) })
+			}
+		}
 	}
 
 	roots, remain := getRoots(args[1:], false)
 	if err := flag.Parse(remain); err != nil {
 		return nil, nil, err
 	}
-	backRoots, _ := getRoots(flag.Args(), len(roots) == 0)
+	backRoots, _ := getRoots(flag.Args(), len(roots) == 0 && (p.indexValue == "" || p.indexValue == "build"))
+	roots = append(roots, backRoots...)
+
+	switch p.indexValue {
+	case "":
+	case "build":
+		walker.indexBuild = true
+		if walker.indexDB == "" {
+			db, err := DefaultIndexPath()
+			if err != nil {
+				return nil, nil, err
+			}
+			walker.indexDB = db
+		}
+	default:
+		index, err := loadIndex(p.indexValue)
+		if err != nil {
+			return nil, nil, err
+		}
+		walker.index, walker.fsys = index, indexFS{index}
+		if len(roots) == 0 {
+			roots = index.roots
+		}
+		for i, root := range roots {
+			if roots[i], err = filepath.Abs(root); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
 
-	walker.matcher = append(walker.matcher, exp)
-	return walker, append(roots, backRoots...), nil
+	// Run runs only one of them, so the others would be ignored.
+	modes := slices.DeleteFunc(walker.outputOptions(), func(o string) bool { return o == "-watch" })
+	if walker.fuzzy != nil {
+		modes = append(modes, "-fuzzy")
+	}
+	if len(modes) > 1 {
+		return nil, nil, fmt.Errorf("%s can't be used with %s", modes[0], strings.Join(modes[1:], ", "))
+	}
+	if walker.IsWatch && (walker.duplicates || walker.manifest != nil || walker.fuzzy != nil) {
+		return nil, nil, fmt.Errorf("-watch can't be used with -duplicates, -verify and -fuzzy")
+	}
+	if walker.IsInteractive && (walker.outputOption() != "" || walker.top > 0) {
+		return nil, nil, fmt.Errorf("-interactive can't be used with other output options such as -watch, -duplicates and -top")
+	}
+	if walker.IsServe && (walker.outputOption() != "" || walker.IsInteractive) {
+		return nil, nil, fmt.Errorf("-serve can't be used with other output options such as -watch, -duplicates and -interactive")
+	}
+	if walker.top > 0 && walker.fuzzy == nil {
+		return nil, nil, fmt.Errorf("-top requires -fuzzy")
+	}
+	for _, f := range p.fileTypes {
+		set, err := walker.fileTypes.Matcher(f.name)
+		if err != nil {
+			return nil, nil, err
+		}
+		f.FileExp = set
+	}
+
+	matcher, err := p.builder.Build()
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, c := range walker.contents {
+		c.SetLimit(p.grepLimit)
+	}
+	for _, s := range p.sizes {
+		s.Round = p.sizeRound
+	}
+	walker.matcher = matcher
+	if walker.index != nil {
+		if err := walker.checkIndexQuery(); err != nil {
+			return nil, nil, err
+		}
+	}
+	return walker, roots, nil
 }
 
-func toFilter(f filter.FileExp, isNot *bool) filter.FileExp {
-	if *isNot {
-		*isNot = false
-		return filter.NewNotExp(f)
+// outputOptions returns the options which print other than matched files.
+func (w *Walker) outputOptions() []string {
+	var opts []string
+	for _, o := range []struct {
+		name string
+		set  bool
+	}{
+		{"-watch", w.IsWatch},
+		{"-duplicates", w.duplicates},
+		{"-verify", w.manifest != nil},
+		{"-snapshot", w.snapshotFile != ""},
+		{"-diff", w.diffBase != nil},
+		{"-index build", w.indexBuild},
+		{"-checksum", w.checksum != nil},
+	} {
+		if o.set {
+			opts = append(opts, o.name)
+		}
 	}
-	return f
+	return opts
+}
+
+// outputOption returns the first of outputOptions, or "" if there is no such option.
+func (w *Walker) outputOption() string {
+	if opts := w.outputOptions(); len(opts) > 0 {
+		return opts[0]
+	}
+	return ""
+}
+
+// Print writes paths in the same way as the matched files, and flushes them.
+func (w *Walker) Print(paths []string) error {
+	for _, path := range paths {
+		sep := "\n"
+		if w.printType == print0 {
+			sep = "\x00"
+		}
+		if _, err := w.out.WriteString(path + sep); err != nil {
+			return err
+		}
+	}
+	return w.out.Flush()
+}
+
+// WriteTypeList writes file types and their glob patterns.
+func (w *Walker) WriteTypeList(out io.Writer) error {
+	for _, name := range w.fileTypes.Names() {
+		if _, err := fmt.Fprintf(out, "%s: %s\n", name, strings.Join(w.fileTypes[name], ", ")); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func getRoots(args []string, leastOne bool) (roots []string, remain []string) {
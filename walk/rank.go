@@ -0,0 +1,93 @@
+package walk
+
+import (
+	"container/heap"
+	"context"
+	"log"
+	"slices"
+	"strings"
+	"time"
+)
+
+type rankedEntry struct {
+	entry *entryInfo
+	score int
+}
+
+// better reports whether a is ranked higher than b.
+// Paths with the same score are ordered by the length and then lexically.
+func (a rankedEntry) better(b rankedEntry) bool {
+	if a.score != b.score {
+		return a.score > b.score
+	}
+	if len(a.entry.path) != len(b.entry.path) {
+		return len(a.entry.path) < len(b.entry.path)
+	}
+	return strings.Compare(a.entry.path, b.entry.path) < 0
+}
+
+// rankedEntries is a heap whose top is the worst entry,
+// so that only the best entries are kept with -top.
+type rankedEntries []rankedEntry
+
+func (r rankedEntries) Len() int           { return len(r) }
+func (r rankedEntries) Less(i, j int) bool { return r[j].better(r[i]) }
+func (r rankedEntries) Swap(i, j int)      { r[i], r[j] = r[j], r[i] }
+func (r *rankedEntries) Push(x any)        { *r = append(*r, x.(rankedEntry)) }
+func (r *rankedEntries) Pop() any {
+	old := *r
+	x := old[len(old)-1]
+	*r = old[:len(old)-1]
+	return x
+}
+
+// rankFile scores the entry by -fuzzy. The entries are written by writeRanked.
+func (w *Walker) rankFile(entry *entryInfo) error {
+	score, _ := w.fuzzy.Score(entry.path)
+	ranked := rankedEntry{entry: entry, score: score}
+
+	w.writingMutex.Lock()
+	defer w.writingMutex.Unlock()
+	if w.top > 0 && len(w.ranked) == w.top {
+		if !ranked.better(w.ranked[0]) {
+			return nil
+		}
+		w.ranked[0] = ranked
+		heap.Fix(&w.ranked, 0)
+		return nil
+	}
+	heap.Push(&w.ranked, ranked)
+	return nil
+}
+
+func (w *Walker) runRanked(roots []string) {
+	w.ranked = nil
+	_ = w.walk(context.Background(), roots, w.rankFile, w.printError)
+
+	w.flushTick = time.NewTicker(time.Millisecond)
+	defer w.flushTick.Stop()
+	for _, entry := range w.sortRanked() {
+		if err := w.writeFile(entry); err != nil {
+			log.Printf("[ERROR] %v", err)
+		}
+	}
+}
+
+// sortRanked returns the entries ranked by rankFile from the best one.
+func (w *Walker) sortRanked() []*entryInfo {
+	ranked := slices.Clone(w.ranked)
+	slices.SortFunc(ranked, func(a, b rankedEntry) int {
+		if a.better(b) {
+			return -1
+		}
+		if b.better(a) {
+			return 1
+		}
+		return 0
+	})
+	entries := make([]*entryInfo, len(ranked))
+	for i, r := range ranked {
+		entries[i] = r.entry
+	}
+	return entries
+}
@@ -0,0 +1,404 @@
+package walk
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+// SearchParams is the params of the search method of -serve.
+type SearchParams struct {
+	// Args are the starting-points, flags and expressions in the same way as the command line.
+	Args []string `json:"args"`
+}
+
+// SearchMatches is the params of the result notification of -serve, which has matched paths of the search.
+type SearchMatches struct {
+	// ID is the id of the search request.
+	ID    json.RawMessage `json:"id"`
+	Paths []string        `json:"paths"`
+}
+
+// SearchResult is the result of the search method of -serve. It is sent after all matched paths.
+type SearchResult struct {
+	Count  int      `json:"count"`
+	Errors []string `json:"errors,omitempty"`
+}
+
+// CancelParams is the params of the cancel method of -serve.
+type CancelParams struct {
+	// ID is the id of the search request to cancel.
+	ID json.RawMessage `json:"id"`
+}
+
+type rpcRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcNotification struct {
+	JSONRPC string `json:"jsonrpc"`
+	Method  string `json:"method"`
+	Params  any    `json:"params"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// error codes of JSON-RPC 2.0, and the code of canceled requests of LSP.
+const (
+	rpcParseError     = -32700
+	rpcInvalidRequest = -32600
+	rpcMethodNotFound = -32601
+	rpcInvalidParams  = -32602
+	rpcCanceled       = -32800
+)
+
+const (
+	// serveBatch is the max number of paths in a result notification.
+	serveBatch = 1000
+	// serveInterval is the interval to send found paths.
+	serveInterval = 50 * time.Millisecond
+)
+
+// Serve answers JSON-RPC 2.0 requests of -serve until in is closed or ctx is done.
+// Requests are read from in and responses are written to the writer given to NewWalkerFromArgs,
+// or both are done with connections to the Unix socket of -serve-socket.
+// Directory listings and .gitignore files are kept in a DirCache shared by searches.
+func (w *Walker) Serve(ctx context.Context, in io.Reader) error {
+	cache := NewDirCache()
+	if w.serveSocket != "" {
+		return serveSocket(ctx, w.serveSocket, cache)
+	}
+	return newServer(w.out, cache).serve(ctx, in)
+}
+
+func serveSocket(ctx context.Context, path string, cache *DirCache) error {
+	// remove the socket left by a previous server
+	if info, err := os.Lstat(path); err == nil && info.Mode().Type() == fs.ModeSocket {
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+	}
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return err
+	}
+	go func() {
+		<-ctx.Done()
+		l.Close()
+	}()
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer conn.Close()
+			if err := newServer(conn, cache).serve(ctx, conn); err != nil {
+				log.Printf("[ERROR] %v", err)
+			}
+		}()
+	}
+}
+
+type server struct {
+	cache *DirCache
+
+	writeMutex sync.Mutex
+	out        *bufio.Writer
+
+	mu       sync.Mutex
+	searches map[string]context.CancelFunc
+	wg       sync.WaitGroup
+	// cancel stops all searches.
+	cancel context.CancelFunc
+}
+
+func newServer(out io.Writer, cache *DirCache) *server {
+	return &server{
+		cache:    cache,
+		out:      bufio.NewWriter(out),
+		searches: make(map[string]context.CancelFunc),
+	}
+}
+
+// serve handles requests until in is closed. Running searches are finished before returning,
+// unless ctx is done.
+func (s *server) serve(ctx context.Context, in io.Reader) error {
+	ctx, s.cancel = context.WithCancel(ctx)
+	defer func() {
+		s.wg.Wait()
+		s.cancel()
+	}()
+
+	type line struct {
+		b   []byte
+		err error
+	}
+	lines := make(chan line)
+	go func() {
+		r := bufio.NewReader(in)
+		for {
+			b, err := r.ReadBytes('\n')
+			select {
+			case lines <- line{b, err}:
+			case <-ctx.Done():
+				return
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			s.cancel()
+			return nil
+		case l := <-lines:
+			if len(l.b) > 0 {
+				s.handle(ctx, l.b)
+			}
+			if errors.Is(l.err, io.EOF) {
+				return nil
+			}
+			if l.err != nil {
+				return l.err
+			}
+		}
+	}
+}
+
+func (s *server) handle(ctx context.Context, b []byte) {
+	var req rpcRequest
+	if err := json.Unmarshal(b, &req); err != nil {
+		s.respondError(nil, rpcParseError, err.Error())
+		return
+	}
+	if req.JSONRPC != "2.0" || req.Method == "" {
+		s.respondError(req.ID, rpcInvalidRequest, "invalid request")
+		return
+	}
+
+	switch req.Method {
+	case "search":
+		s.search(ctx, req)
+	case "cancel":
+		var params CancelParams
+		if err := json.Unmarshal(req.Params, &params); err != nil || params.ID == nil {
+			if req.ID != nil {
+				s.respondError(req.ID, rpcInvalidParams, "cancel requires the id of a search")
+			}
+			return
+		}
+		s.mu.Lock()
+		cancel, ok := s.searches[string(params.ID)]
+		s.mu.Unlock()
+		if ok {
+			cancel()
+		}
+		// a notification is not answered
+		if req.ID != nil {
+			s.respond(req.ID, ok)
+		}
+	default:
+		s.respondError(req.ID, rpcMethodNotFound, fmt.Sprintf("method not found: %s", req.Method))
+	}
+}
+
+func (s *server) search(ctx context.Context, req rpcRequest) {
+	if req.ID == nil {
+		// a notification can't receive results
+		return
+	}
+	var params SearchParams
+	if err := json.Unmarshal(req.Params, &params); err != nil {
+		s.respondError(req.ID, rpcInvalidParams, err.Error())
+		return
+	}
+	walker, roots, err := newWalkerFromArgs(append([]string{"fing"}, params.Args...), io.Discard, io.Discard, false)
+	if err == nil {
+		err = walker.checkSearch()
+	}
+	if err != nil {
+		s.respondError(req.ID, rpcInvalidParams, err.Error())
+		return
+	}
+	walker.cache = s.cache
+
+	key := string(req.ID)
+	ctx, cancel := context.WithCancel(ctx)
+	s.mu.Lock()
+	if _, ok := s.searches[key]; ok {
+		s.mu.Unlock()
+		cancel()
+		s.respondError(req.ID, rpcInvalidRequest, fmt.Sprintf("search %s is running", key))
+		return
+	}
+	s.searches[key] = cancel
+	s.mu.Unlock()
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer func() {
+			s.mu.Lock()
+			delete(s.searches, key)
+			s.mu.Unlock()
+			cancel()
+			if err := walker.Close(); err != nil {
+				log.Printf("[ERROR] %v", err)
+			}
+		}()
+		s.runSearch(ctx, req.ID, walker, roots)
+	}()
+}
+
+// checkSearch returns an error if the walker has options which the search of -serve doesn't support.
+func (w *Walker) checkSearch() error {
+	if opt := w.outputOption(); opt != "" {
+		return fmt.Errorf("%s can't be used in a search", opt)
+	}
+	if w.IsDry || w.IsTypeList || w.IsInteractive || w.IsServe || w.grepPrint {
+		return fmt.Errorf("-dry, -type-list, -interactive, -serve and -grep-print can't be used in a search")
+	}
+	if len(w.commands) > 0 {
+		// clients of the socket must not run programs as the server.
+		return fmt.Errorf("-filter-cmd and -filter-coproc can't be used in a search")
+	}
+	return nil
+}
+
+func (s *server) runSearch(ctx context.Context, id json.RawMessage, w *Walker, roots []string) {
+	var (
+		mu     sync.Mutex
+		paths  []string
+		result SearchResult
+	)
+	flush := func() {
+		if len(paths) > 0 {
+			s.notify("result", &SearchMatches{ID: id, Paths: paths})
+			paths = nil
+		}
+	}
+	add := func(path string) {
+		mu.Lock()
+		defer mu.Unlock()
+		paths = append(paths, path)
+		result.Count++
+		if len(paths) == serveBatch {
+			flush()
+		}
+	}
+	onError := func(err error) {
+		mu.Lock()
+		result.Errors = append(result.Errors, err.Error())
+		mu.Unlock()
+	}
+
+	var err error
+	if w.fuzzy != nil {
+		if err = w.walk(ctx, roots, w.rankFile, onError); err == nil {
+			for _, entry := range w.sortRanked() {
+				add(entry.path)
+			}
+		}
+	} else {
+		tick := time.NewTicker(serveInterval)
+		done := make(chan struct{})
+		go func() {
+			for {
+				select {
+				case <-tick.C:
+					mu.Lock()
+					flush()
+					mu.Unlock()
+				case <-done:
+					return
+				}
+			}
+		}()
+		err = w.walk(ctx, roots, func(e *entryInfo) error {
+			add(e.path)
+			return nil
+		}, onError)
+		tick.Stop()
+		close(done)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if err != nil {
+		s.respondError(id, rpcCanceled, "search canceled")
+		return
+	}
+	flush()
+	s.respond(id, &result)
+}
+
+func (s *server) respond(id json.RawMessage, result any) {
+	b, err := json.Marshal(result)
+	if err != nil {
+		s.respondError(id, rpcInvalidRequest, err.Error())
+		return
+	}
+	s.write(&rpcResponse{JSONRPC: "2.0", ID: id, Result: b})
+}
+
+func (s *server) respondError(id json.RawMessage, code int, message string) {
+	if id == nil {
+		id = json.RawMessage("null")
+	}
+	s.write(&rpcResponse{JSONRPC: "2.0", ID: id, Error: &rpcError{Code: code, Message: message}})
+}
+
+func (s *server) notify(method string, params any) {
+	s.write(&rpcNotification{JSONRPC: "2.0", Method: method, Params: params})
+}
+
+func (s *server) write(v any) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		log.Printf("[ERROR] %v", err)
+		return
+	}
+	s.writeMutex.Lock()
+	defer s.writeMutex.Unlock()
+	if _, err = s.out.Write(append(b, '\n')); err == nil {
+		err = s.out.Flush()
+	}
+	if err != nil {
+		// the client is gone
+		s.cancel()
+	}
+}
@@ -0,0 +1,193 @@
+package walk
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWalker_Serve(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, "a.txt", "b.go", "sub/c.txt")
+	requests := []string{
+		`{"jsonrpc":"2.0","id":1,"method":"search","params":{"args":["` + dir + `","-name","*.txt"]}}`,
+		`{"jsonrpc":"2.0","id":"fuzzy","method":"search","params":{"args":["` + dir + `","-type","f","-fuzzy","bgo"]}}`,
+		`{"jsonrpc":"2.0","id":2,"method":"search","params":{"args":["-watch"]}}`,
+		`{"jsonrpc":"2.0","id":3,"method":"search","params":{"args":["-unknown"]}}`,
+		`{"jsonrpc":"2.0","id":4,"method":"unknown"}`,
+		`{"jsonrpc":"2.0","id":5,"method":"cancel","params":{"id":100}}`,
+		`{"jsonrpc":"2.0","method":"cancel","params":{"id":100}}`,
+		`{"jsonrpc":"2.0","id":6,"method":"search","params":{"args":["` + dir + `","-filter-cmd","true"]}}`,
+		`{`,
+	}
+
+	walker, _, err := NewWalkerFromArgs([]string{"fing", "-serve"}, new(bytes.Buffer), new(bytes.Buffer))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := new(bytes.Buffer)
+	walker.out.Reset(out)
+	if err := walker.Serve(context.Background(), strings.NewReader(strings.Join(requests, "\n")+"\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		paths     = make(map[string][]string)
+		results   = make(map[string]string)
+		errorCode = make(map[string]int)
+	)
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		var msg struct {
+			ID     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+			Params SearchMatches   `json:"params"`
+			Result json.RawMessage `json:"result"`
+			Error  *rpcError       `json:"error"`
+		}
+		if err := json.Unmarshal([]byte(line), &msg); err != nil {
+			t.Fatalf("%s: %v", line, err)
+		}
+		switch {
+		case msg.Method == "result":
+			paths[string(msg.Params.ID)] = append(paths[string(msg.Params.ID)], msg.Params.Paths...)
+		case msg.Error != nil:
+			errorCode[string(msg.ID)] = msg.Error.Code
+		default:
+			results[string(msg.ID)] = string(msg.Result)
+		}
+	}
+
+	slices.Sort(paths["1"])
+	if want := []string{filepath.Join(dir, "a.txt"), filepath.Join(dir, "sub", "c.txt")}; !slices.Equal(paths["1"], want) {
+		t.Errorf("search paths want %v, got %v", want, paths["1"])
+	}
+	if want := `{"count":2}`; results["1"] != want {
+		t.Errorf("search result want %s, got %s", want, results["1"])
+	}
+	if want := []string{filepath.Join(dir, "b.go")}; !slices.Equal(paths[`"fuzzy"`], want) {
+		t.Errorf("fuzzy paths want %v, got %v", want, paths[`"fuzzy"`])
+	}
+	if want := "false"; results["5"] != want {
+		t.Errorf("cancel result want %s, got %s", want, results["5"])
+	}
+	if result, ok := results["null"]; ok {
+		t.Errorf("cancel notification is answered by %s", result)
+	}
+	for id, code := range map[string]int{
+		"2":    rpcInvalidParams,
+		"3":    rpcInvalidParams,
+		"4":    rpcMethodNotFound,
+		"6":    rpcInvalidParams,
+		"null": rpcParseError,
+	} {
+		if errorCode[id] != code {
+			t.Errorf("error of %s want %d, got %d", id, code, errorCode[id])
+		}
+	}
+}
+
+func TestDirCache(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "a.txt"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// a listing modified just now is not cached, because a file may be created at the same tick.
+	old := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(dir, time.Time{}, old); err != nil {
+		t.Fatal(err)
+	}
+	opts := DefaultOptions()
+	opts.Cache = NewDirCache()
+	walker := NewWalker(opts)
+	walk := func() []string {
+		t.Helper()
+		var paths []string
+		if err := walker.Walk(context.Background(), []string{dir}, func(e Entry) error {
+			paths = append(paths, filepath.Base(e.Path))
+			return nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+		slices.Sort(paths)
+		return paths
+	}
+	if got, want := walk(), []string{filepath.Base(dir), "a.txt"}; !slices.Equal(got, want) {
+		t.Fatalf("want %v, got %v", want, got)
+	}
+
+	// the listing is reused while the modification time of the directory is the same.
+	if err := os.WriteFile(filepath.Join(dir, "b.txt"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(dir, time.Time{}, old); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := walk(), []string{filepath.Base(dir), "a.txt"}; !slices.Equal(got, want) {
+		t.Errorf("cached want %v, got %v", want, got)
+	}
+
+	if err := os.Chtimes(dir, time.Time{}, old.Add(time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := walk(), []string{filepath.Base(dir), "a.txt", "b.txt"}; !slices.Equal(got, want) {
+		t.Errorf("modified want %v, got %v", want, got)
+	}
+
+	// c.txt is created at the same modification time as the listing read just now.
+	now := time.Now()
+	if err := os.Chtimes(dir, time.Time{}, now); err != nil {
+		t.Fatal(err)
+	}
+	walk()
+	if err := os.WriteFile(filepath.Join(dir, "c.txt"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(dir, time.Time{}, now); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := walk(), []string{filepath.Base(dir), "a.txt", "b.txt", "c.txt"}; !slices.Equal(got, want) {
+		t.Errorf("created at the same time want %v, got %v", want, got)
+	}
+}
+
+func TestDirCache_evict(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, "a/1", "a/2", "b/1", "c/1")
+	for _, name := range []string{"a", "b", "c"} {
+		if err := os.Chtimes(filepath.Join(dir, name), time.Time{}, time.Now().Add(-time.Hour)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cache := NewDirCache()
+	cache.SetLimit(3)
+	for _, name := range []string{"a", "b", "a", "c"} {
+		if _, err := cache.readDir(filepath.Join(dir, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// b is the least recently used.
+	for name, cached := range map[string]bool{"a": true, "b": false, "c": true} {
+		if _, ok := cache.dirs[filepath.Join(dir, name)]; ok != cached {
+			t.Errorf("%s is cached: %t, want %t", name, ok, cached)
+		}
+	}
+	if cache.entries != 3 {
+		t.Errorf("cached entries want 3, but got %d", cache.entries)
+	}
+
+	if err := os.RemoveAll(filepath.Join(dir, "a")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cache.readDir(filepath.Join(dir, "a")); err == nil {
+		t.Fatal("readDir of a removed directory want error")
+	}
+	if _, ok := cache.dirs[filepath.Join(dir, "a")]; ok || cache.entries != 1 {
+		t.Errorf("a removed directory is still cached")
+	}
+}
@@ -0,0 +1,161 @@
+package walk
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// DiffResult is an entry which is different from the snapshot of -diff.
+type DiffResult struct {
+	// Status is added, removed or changed.
+	Status string `json:"status"`
+	Path   string `json:"path"`
+	// Changes are the changed metadata, which are size, mode and mtime.
+	Changes []string   `json:"changes,omitempty"`
+	Old     *EntryMeta `json:"old,omitempty"`
+	New     *EntryMeta `json:"new,omitempty"`
+}
+
+// EntryMeta is the metadata of an entry in the snapshot.
+type EntryMeta struct {
+	Size    int64     `json:"size"`
+	Mode    string    `json:"mode"`
+	ModTime time.Time `json:"mtime"`
+}
+
+func newEntryMeta(f *indexFile) *EntryMeta {
+	return &EntryMeta{Size: f.size, Mode: f.mode.String(), ModTime: f.modTime}
+}
+
+// collectEntry records the metadata of the entry for -snapshot and -diff.
+func (w *Walker) collectEntry(entry *entryInfo) error {
+	info, err := entry.Info()
+	if err != nil {
+		w.writeError(entry.path, err)
+		return nil
+	}
+	f := newIndexFile(info)
+	f.name = filepath.Base(entry.path)
+
+	w.writingMutex.Lock()
+	w.snapshot[entry.path] = f
+	w.writingMutex.Unlock()
+	return nil
+}
+
+// snapshotIndex stores entries in the index grouped by the parent directory.
+func snapshotIndex(roots []string, entries map[string]*indexFile) *index {
+	x := newIndex(roots)
+	for path, f := range entries {
+		parent := filepath.Dir(path)
+		dir, ok := x.dirs[parent]
+		if !ok {
+			dir = &indexDir{}
+			x.dirs[parent] = dir
+		}
+		dir.entries = append(dir.entries, f)
+	}
+	for _, dir := range x.dirs {
+		slices.SortFunc(dir.entries, func(a, b *indexFile) int { return strings.Compare(a.name, b.name) })
+	}
+	return x
+}
+
+// files returns entries of the snapshot by the path.
+func (x *index) files() map[string]*indexFile {
+	files := make(map[string]*indexFile)
+	for path, dir := range x.dirs {
+		for _, f := range dir.entries {
+			files[filepath.Join(path, f.name)] = f
+		}
+	}
+	return files
+}
+
+func (w *Walker) collectSnapshot(roots []string) {
+	w.snapshot = make(map[string]*indexFile)
+	_ = w.walk(context.Background(), roots, w.collectEntry, w.printError)
+}
+
+func (w *Walker) runSnapshot(roots []string) {
+	w.collectSnapshot(roots)
+	if err := snapshotIndex(roots, w.snapshot).save(w.snapshotFile); err != nil {
+		w.writeError(w.snapshotFile, err)
+		return
+	}
+	if _, err := fmt.Fprintf(w.out, "%s: %d entries\n", w.snapshotFile, len(w.snapshot)); err != nil {
+		log.Printf("[ERROR] %v", err)
+	}
+}
+
+// runDiff compares matched entries with the snapshot, and prints differences in the order of paths.
+func (w *Walker) runDiff(roots []string) {
+	w.collectSnapshot(roots)
+	prev := w.diffBase.files()
+
+	var results []DiffResult
+	for path, f := range w.snapshot {
+		old, ok := prev[path]
+		if !ok {
+			results = append(results, DiffResult{Status: "added", Path: path, New: newEntryMeta(f)})
+			continue
+		}
+		if changes := metaChanges(old, f); len(changes) > 0 {
+			results = append(results, DiffResult{Status: "changed", Path: path, Changes: changes, Old: newEntryMeta(old), New: newEntryMeta(f)})
+		}
+	}
+	for path, f := range prev {
+		if _, ok := w.snapshot[path]; !ok {
+			results = append(results, DiffResult{Status: "removed", Path: path, Old: newEntryMeta(f)})
+		}
+	}
+	slices.SortFunc(results, func(a, b DiffResult) int { return strings.Compare(a.Path, b.Path) })
+
+	for _, r := range results {
+		w.IsErr = true
+		if err := w.writeDiffResult(r); err != nil {
+			log.Printf("[ERROR] %v", err)
+			return
+		}
+	}
+}
+
+func metaChanges(old, f *indexFile) []string {
+	var changes []string
+	// the size of a directory depends on the file system.
+	if old.size != f.size && !f.IsDir() {
+		changes = append(changes, "size")
+	}
+	if old.mode != f.mode {
+		changes = append(changes, "mode")
+	}
+	if !old.modTime.Equal(f.modTime) {
+		changes = append(changes, "mtime")
+	}
+	return changes
+}
+
+var diffMarks = map[string]string{"added": "A", "removed": "D", "changed": "M"}
+
+func (w *Walker) writeDiffResult(r DiffResult) error {
+	if w.json {
+		b, err := json.Marshal(r)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w.out, "%s\n", b)
+		return err
+	}
+	if len(r.Changes) > 0 {
+		_, err := fmt.Fprintf(w.out, "%s %s (%s)\n", diffMarks[r.Status], r.Path, strings.Join(r.Changes, ","))
+		return err
+	}
+	_, err := fmt.Fprintf(w.out, "%s %s\n", diffMarks[r.Status], r.Path)
+	return err
+}
@@ -0,0 +1,60 @@
+package walk
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWalker_Run_diff(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, "a.txt", "b.txt", "c.txt", "sub/d.txt")
+	snapshot := filepath.Join(t.TempDir(), "snapshot")
+	run := func(args ...string) (string, bool) {
+		t.Helper()
+		out := new(bytes.Buffer)
+		walker, roots, err := NewWalkerFromArgs(append([]string{"fing", dir, "-type", "f"}, args...), out, out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		walker.Run(roots)
+		return out.String(), walker.IsErr
+	}
+
+	if out, isErr := run("-snapshot", snapshot); isErr {
+		t.Fatal(out)
+	}
+	if out, isErr := run("-diff", snapshot); isErr || out != "" {
+		t.Errorf("diff without changes: %s", out)
+	}
+
+	if err := os.Remove(filepath.Join(dir, "a.txt")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "b.txt"), []byte("bb"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(filepath.Join(dir, "b.txt"), time.Time{}, time.Unix(0, 0)); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(filepath.Join(dir, "c.txt"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "sub", "e.txt"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	out, isErr := run("-diff", snapshot)
+	if !isErr {
+		t.Errorf("diff want error status")
+	}
+	want := "D " + filepath.Join(dir, "a.txt") + "\n" +
+		"M " + filepath.Join(dir, "b.txt") + " (size,mtime)\n" +
+		"M " + filepath.Join(dir, "c.txt") + " (mode)\n" +
+		"A " + filepath.Join(dir, "sub", "e.txt") + "\n"
+	if out != want {
+		t.Errorf("diff want:\n%s\ngot:\n%s", want, out)
+	}
+}
@@ -2,12 +2,16 @@ package walk
 
 import (
 	"bufio"
+	"context"
 	"errors"
 	"fmt"
+	"hash"
 	"io"
 	"io/fs"
+	"iter"
 	"log"
 	"os"
+	"path"
 	"path/filepath"
 	"runtime"
 	"slices"
@@ -31,15 +35,26 @@ const fingignoreFile = ".fingignore"
 
 type Walker struct {
 	// matcher
-	matcher      filter.OrExp
-	prunes       filter.OrExp
+	matcher      filter.FileExp
+	prunes       filter.FileExp
+	hasPrint     bool
 	globalIgnore *filter.Gitignore
 
 	// options
-	IsDry      bool
-	ignoreFile bool
-	depth      int
-	ignoreErr  bool
+	fsys          fs.FS
+	cache         *DirCache
+	archives      bool
+	IsDry         bool
+	IsTypeList    bool
+	IsWatch       bool
+	IsInteractive bool
+	IsServe       bool
+	IsCompletion  bool
+	ignoreFile    bool
+	skipHidden    bool
+	depth         int
+	xdev          bool
+	ignoreErr     bool
 
 	// result
 	out         *bufio.Writer
@@ -50,6 +65,57 @@ type Walker struct {
 
 	// print
 	printType printType
+	json      bool
+	grepPrint bool
+	contents  []*filter.Content
+	fileTypes filter.FileTypes
+
+	// rank
+	fuzzy  *filter.Fuzzy
+	top    int
+	ranked rankedEntries
+
+	// duplicates
+	duplicates bool
+	dupCompare bool
+	collected  []*dupFile
+	inodes     map[fileID]*dupFile
+
+	// checksum
+	checksum func() hash.Hash
+	manifest *manifest
+	verified map[string]struct{}
+
+	// watch
+	watchDirs []*watchDir
+
+	// index
+	indexBuild bool
+	indexDB    string
+	index      *index
+
+	// snapshot
+	snapshotFile string
+	diffBase     *index
+	snapshot     map[string]*indexFile
+
+	// serve
+	serveSocket string
+
+	// completion
+	completionShell string
+
+	// closers are helpers of expressions closed by Close.
+	closers []io.Closer
+	// commands are expressions which run commands, which are killed when the walk is canceled.
+	commands []filter.CommandExp
+
+	// per walk
+	ctx     context.Context
+	cancel  context.CancelFunc
+	stopErr error
+	onMatch func(*entryInfo) error
+	onError func(error)
 
 	// concurrency control
 	writingMutex sync.Mutex
@@ -58,66 +124,303 @@ type Walker struct {
 	fmt.Stringer
 }
 
+// Options configures a Walker created by NewWalker.
+type Options struct {
+	// Matcher selects entries passed to the callback. A nil Matcher matches all entries.
+	Matcher filter.FileExp
+	// Prune stops descending into directories which it matches.
+	Prune filter.FileExp
+	// MaxDepth is the depth to descend from the roots. A negative value such as NoLimit disables the limit.
+	MaxDepth int
+	// Gitignore skips entries ignored by .gitignore and ~/.fingignore.
+	Gitignore bool
+	// SkipHidden skips entries whose name starts with a dot. Roots are never skipped.
+	SkipHidden bool
+	// IgnoreErrors drops errors on opening files, such as permission errors.
+	IgnoreErrors bool
+	// Archives descends into zip, jar, tar, tar.gz and tgz files like directories.
+	// Paths of members are joined to the path of the archive by "!/".
+	Archives bool
+	// XDev doesn't descend into directories on other file systems than the root, like find -xdev.
+	XDev bool
+	// FS is the file system to walk. Roots and paths of entries are slash-separated paths of FS.
+	// A nil FS walks the OS file system directly, which is faster than os.DirFS.
+	FS fs.FS
+	// Cache reuses directory listings and .gitignore files of the OS file system read by previous walks.
+	// Directories which are not modified since the previous walk are not read again.
+	Cache *DirCache
+}
+
+// NoLimit is the MaxDepth which doesn't limit the depth.
+const NoLimit = -1
+
+// Entry is a file found by the Walker.
+type Entry struct {
+	Path string
+	fs.DirEntry
+}
+
+// Error is an error which occurred while walking Path.
+type Error struct {
+	Path string
+	Err  error
+}
+
 type entryInfo struct {
 	path        string
 	ignore      *filter.Gitignore
 	info        fs.DirEntry
 	projectRoot string
+
+	// fsys is the file system containing the entry and name is the path in it.
+	// A nil fsys is the OS file system.
+	fsys fs.FS
+	name string
+	// prefix is the path of the archive containing the entry, which is prepended to name.
+	prefix  string
+	archive archiveKind
+	// depth is the depth from the root.
+	depth int
+	// rootDev is the device of the root for -xdev, and rootDevOK reports whether it is known.
+	rootDev   uint64
+	rootDevOK bool
+
+	// walker reads the directory by ReadDir, and listing is kept for scanDir.
+	walker  *Walker
+	listing []fs.DirEntry
+	listErr error
+	listed  bool
+
+	// pruned and prints are set by the actions of the expression.
+	pruned bool
+	prints []string
 }
 
 type entryInfos []*entryInfo
 
-func (w *Walker) Walk(roots []string) {
-	w.flushTick = time.NewTicker(time.Millisecond)
-	defer w.flushTick.Stop()
+var (
+	_ fs.DirEntry      = (*entryInfo)(nil)
+	_ filter.Opener    = (*entryInfo)(nil)
+	_ filter.Depther   = (*entryInfo)(nil)
+	_ filter.DirReader = (*entryInfo)(nil)
+	_ filter.Actor     = (*entryInfo)(nil)
+)
+
+// DefaultOptions returns Options which match all entries without depth limit.
+func DefaultOptions() Options {
+	return Options{MaxDepth: NoLimit}
+}
+
+// NewWalker creates a Walker from opts. Run prints to os.Stdout and os.Stderr.
+func NewWalker(opts Options) *Walker {
+	return &Walker{
+		out:        bufio.NewWriter(os.Stdout),
+		outerr:     os.Stderr,
+		printType:  println,
+		matcher:    opts.Matcher,
+		prunes:     opts.Prune,
+		depth:      opts.MaxDepth,
+		ignoreFile: opts.Gitignore,
+		skipHidden: opts.SkipHidden,
+		ignoreErr:  opts.IgnoreErrors,
+		fsys:       opts.FS,
+		archives:   opts.Archives,
+		xdev:       opts.XDev,
+		cache:      opts.Cache,
+	}
+}
+
+// SetCache sets the DirCache used by following walks. See Options.Cache.
+func (w *Walker) SetCache(cache *DirCache) {
+	w.cache = cache
+}
+
+// Run walks roots and prints matched files to the writer given to NewWalkerFromArgs, or os.Stdout for NewWalker.
+func (w *Walker) Run(roots []string) {
+	switch {
+	case w.indexBuild:
+		w.runBuildIndex(roots)
+	case w.snapshotFile != "":
+		w.runSnapshot(roots)
+	case w.diffBase != nil:
+		w.runDiff(roots)
+	case w.duplicates:
+		w.runDuplicates(roots)
+	case w.manifest != nil:
+		w.runVerify(roots)
+	case w.fuzzy != nil:
+		w.runRanked(roots)
+	default:
+		w.flushTick = time.NewTicker(time.Millisecond)
+		defer w.flushTick.Stop()
+		_ = w.walk(context.Background(), roots, w.writeFile, w.printError)
+	}
+
+	if err := w.out.Flush(); err != nil {
+		log.Printf("[ERROR] %v", err)
+	}
+}
+
+// setCommandContext kills the commands of expressions when ctx is done.
+// It is not the context of a walk, because -filter-coproc keeps its helper across walks such as -watch.
+func (w *Walker) setCommandContext(ctx context.Context) {
+	for _, c := range w.commands {
+		c.SetContext(ctx, w.outerr)
+	}
+}
+
+// Close stops helper processes of expressions such as -filter-coproc.
+func (w *Walker) Close() error {
+	var errs []error
+	for _, c := range w.closers {
+		errs = append(errs, c.Close())
+	}
+	w.closers = nil
+	return errors.Join(errs...)
+}
+
+func (w *Walker) printError(err error) {
+	if _, err := w.outerr.Write([]byte(err.Error() + "\n")); err != nil {
+		log.Printf("[ERROR] %v", err)
+	}
+}
+
+// Walk walks roots and calls fn with each matched entry.
+// fn is called by one goroutine at a time, but not in a lexical order.
+//
+// Walk stops when fn returns an error or ctx is done, and returns that error.
+// Otherwise, errors which occurred while walking are returned together as *Error.
+// Walker must not be used by several walks at the same time.
+func (w *Walker) Walk(ctx context.Context, roots []string, fn func(Entry) error) error {
+	var errs []error
+	if err := w.walk(ctx, roots,
+		func(e *entryInfo) error {
+			w.writingMutex.Lock()
+			defer w.writingMutex.Unlock()
+			// another goroutine may be here before the walk is canceled by the error.
+			if w.stopErr != nil {
+				return w.stopErr
+			}
+			if err := fn(e.entry()); err != nil {
+				w.stopErr = err
+				return err
+			}
+			return nil
+		},
+		func(err error) { errs = append(errs, err) },
+	); err != nil {
+		return err
+	}
+	return errors.Join(errs...)
+}
+
+// All returns an iterator over matched entries of roots.
+// Errors which occurred while walking are yielded with the entry of the path.
+// Breaking the loop stops the walk.
+func (w *Walker) All(ctx context.Context, roots []string) iter.Seq2[Entry, error] {
+	type result struct {
+		entry Entry
+		err   error
+	}
+	return func(yield func(Entry, error) bool) {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		results := make(chan result)
+		send := func(r result) error {
+			select {
+			case results <- r:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		go func() {
+			defer close(results)
+			err := w.walk(ctx, roots,
+				func(e *entryInfo) error { return send(result{entry: e.entry()}) },
+				func(err error) {
+					var walkErr *Error
+					if errors.As(err, &walkErr) {
+						_ = send(result{entry: Entry{Path: walkErr.Path}, err: err})
+					} else {
+						_ = send(result{err: err})
+					}
+				},
+			)
+			if err != nil && ctx.Err() == nil {
+				_ = send(result{err: err})
+			}
+		}()
+
+		for r := range results {
+			if !yield(r.entry, r.err) {
+				cancel()
+				for range results {
+				}
+				return
+			}
+		}
+		if err := ctx.Err(); err != nil {
+			yield(Entry{}, err)
+		}
+	}
+}
+
+func (w *Walker) walk(ctx context.Context, roots []string, onMatch func(*entryInfo) error, onError func(error)) error {
+	w.ctx, w.cancel = context.WithCancel(ctx)
+	defer w.cancel()
+	w.setCommandContext(ctx)
+	w.stopErr = nil
+	w.onMatch, w.onError = onMatch, onError
+	w.hasPrint = w.matcher != nil && filter.HasPrint(w.matcher)
+	w.globalIgnore = nil
+	w.directories = w.directories[:0]
 
 	home, err := os.UserHomeDir()
 	if err != nil {
-		w.writeError(err)
-		return
+		w.writeError("", err)
+		return nil
 	}
 	ignorepath := filepath.Join(home, fingignoreFile)
 	if _, err := os.Stat(ignorepath); err != nil {
 		if !errors.Is(err, os.ErrNotExist) {
-			w.writeError(err)
-			return
+			w.writeError(ignorepath, err)
+			return nil
 		}
 	} else {
 		for _, root := range roots {
-			ignore, err := filter.NewGitIgnore(root, ignorepath)
+			ignore, err := w.newGitIgnore(root, ignorepath)
 			if err != nil {
-				w.writeError(err)
-				return
+				w.writeError(ignorepath, err)
+				return nil
 			}
 			w.globalIgnore = w.globalIgnore.Add(ignore)
 		}
 	}
 
 	for _, r := range roots {
-		f, err := os.Open(r)
-		if err != nil {
-			w.writeError(err)
-			continue
+		if w.ctx.Err() != nil {
+			break
 		}
-		entry, err := newEntry(f)
-		f.Close()
+		entry, err := w.stat(r)
 		if err != nil {
-			w.writeError(err)
+			w.writeError(r, err)
 			continue
 		}
 		var (
 			ignore          *filter.Gitignore
-			projectRootPath = []string{f.Name()}
+			projectRootPath = []string{r}
 			projectRoot     string
 		)
-		if w.ignoreFile {
+		if w.ignoreFile && w.fsys == nil {
 			for parent := filepath.Join("..", r); ; parent = filepath.Join("..", parent) {
 				dir, err := os.Open(parent)
 				if err != nil {
 					break
 				}
 				projectRootPath = append(projectRootPath, dir.Name())
-				gitignore, err := filter.NewGitIgnore(".", filepath.Join(parent, ".gitignore"))
+				gitignore, err := w.newGitIgnore(".", filepath.Join(parent, ".gitignore"))
 				if err == nil {
 					ignore = gitignore
 					slices.Reverse(projectRootPath)
@@ -131,7 +434,7 @@ func (w *Walker) Walk(roots []string) {
 			}
 		}
 
-		w.checkEntry(&entryInfo{path: r, info: entry, ignore: ignore, projectRoot: projectRoot})
+		w.checkEntry(&entryInfo{path: r, info: entry, ignore: ignore, projectRoot: projectRoot, fsys: w.fsys, name: r})
 	}
 
 	var wg sync.WaitGroup
@@ -153,7 +456,7 @@ func (w *Walker) Walk(roots []string) {
 	}
 
 	var entries entryInfos
-	for depth := 1; len(w.directories) > 0 && (w.depth == -1 || depth <= w.depth); depth++ {
+	for depth := 1; len(w.directories) > 0 && (w.depth < 0 || depth <= w.depth) && w.ctx.Err() == nil; depth++ {
 		if cap(entries) >= len(w.directories) {
 			entries = entries[:len(w.directories)]
 		} else {
@@ -179,9 +482,10 @@ func (w *Walker) Walk(roots []string) {
 		wg.Wait()
 	}
 
-	if err := w.out.Flush(); err != nil {
-		log.Printf("[ERROR] %v", err)
+	if w.stopErr != nil {
+		return w.stopErr
 	}
+	return ctx.Err()
 }
 
 func (w *Walker) String() string {
@@ -189,102 +493,263 @@ func (w *Walker) String() string {
 	if w.ignoreFile {
 		s.WriteString("ignore=true ")
 	}
-	if w.depth != -1 {
+	if w.skipHidden {
+		s.WriteString("hidden=false ")
+	}
+	if w.archives {
+		s.WriteString("archives=true ")
+	}
+	if w.depth >= 0 {
 		fmt.Fprintf(&s, "maxdepth=%d ", w.depth)
 	}
-	if len(w.prunes) > 0 {
+	if w.xdev {
+		s.WriteString("xdev=true ")
+	}
+	if w.prunes != nil {
 		fmt.Fprintf(&s, "prunes=[%s] ", w.prunes)
 	}
-	if len(w.matcher) > 0 {
+	if w.indexBuild {
+		fmt.Fprintf(&s, "index=build(%s) ", w.indexDB)
+	}
+	if w.index != nil {
+		s.WriteString("index=true ")
+	}
+	if w.snapshotFile != "" {
+		fmt.Fprintf(&s, "snapshot=%s ", w.snapshotFile)
+	}
+	if w.diffBase != nil {
+		s.WriteString("diff=true ")
+	}
+	if w.duplicates {
+		s.WriteString("duplicates=true ")
+	}
+	if w.manifest != nil {
+		fmt.Fprintf(&s, "verify=%s ", w.manifest.algo)
+	}
+	if w.top > 0 {
+		fmt.Fprintf(&s, "top=%d ", w.top)
+	}
+	if w.matcher != nil {
 		fmt.Fprintf(&s, "condition=[%s]", w.matcher)
 	}
 	return s.String()
 }
 
 func (w *Walker) checkEntry(entry *entryInfo) {
+	if w.ctx.Err() != nil {
+		return
+	}
+	entry.walker = w
 	ignore := entry.ignore.Add(w.globalIgnore)
 	if ignore != nil {
 		if match, _ := ignore.Match(filepath.Join(entry.projectRoot, entry.path), entry.info); match {
 			return
 		}
 	}
-	match, err := w.matcher.Match(entry.path, entry.info)
-	if err != nil {
-		w.writeError(err)
-		return
+	match := true
+	if w.matcher != nil {
+		var err error
+		match, err = w.matcher.Match(entry.path, entry)
+		if err != nil {
+			w.writeError(entry.path, err)
+			return
+		}
+		if w.hasPrint {
+			match = len(entry.prints) > 0
+		}
 	}
-	if match {
-		w.writeFile(entry.path, entry.info)
+	if match && w.ctx.Err() == nil {
+		if err := w.onMatch(entry); err != nil {
+			w.stop(err)
+			return
+		}
 	}
 
-	if entry.info.IsDir() {
-		w.dirMutex.Lock()
-		w.directories = append(w.directories, entry)
-		w.dirMutex.Unlock()
+	if !entry.info.IsDir() {
+		if !w.archives || !entry.info.Type().IsRegular() {
+			return
+		}
+		if entry.archive = archiveKindOf(entry.info.Name()); entry.archive == notArchive {
+			return
+		}
 	}
+	if entry.pruned || w.xdev && !onRootDevice(entry) {
+		return
+	}
+	w.dirMutex.Lock()
+	w.directories = append(w.directories, entry)
+	w.dirMutex.Unlock()
 }
 
 func (w *Walker) scanDir(entry *entryInfo) {
-	if entry.path != "." {
-		match, err := w.prunes.Match(entry.path, entry.info)
+	if w.ctx.Err() != nil {
+		return
+	}
+	if entry.path != "." && w.prunes != nil {
+		match, err := w.prunes.Match(entry.path, entry)
 		if err != nil {
-			w.writeError(err)
+			w.writeError(entry.path, err)
+			return
+		}
+		if match {
 			return
 		}
-		if len(w.prunes) > 0 && match {
+	}
+
+	if entry.archive != notArchive {
+		fsys, err := openArchive(entry.fsys, entry.name, entry.archive)
+		if err != nil {
+			w.writeError(entry.path, err)
 			return
 		}
+		entry = &entryInfo{path: entry.path, info: entry.info, fsys: fsys, name: ".", prefix: entry.path + archiveSeparator, depth: entry.depth}
 	}
 
-	files, err := w.readDir(entry.path)
+	files, err := w.readDir(entry)
 	if err != nil {
-		w.writeError(err)
+		w.writeError(entry.path, err)
 		return
 	}
 
 	var newIgnore *filter.Gitignore
-	if w.ignoreFile {
+	if w.ignoreFile && entry.prefix == "" {
 		ignoreFile := w.getIgnore(files)
 		if ignoreFile != "" {
-			newIgnore, err = filter.NewGitIgnore(filepath.Join(entry.projectRoot, entry.path), filepath.Join(entry.path, ignoreFile))
+			newIgnore, err = w.readIgnore(entry, ignoreFile)
 			if err != nil {
-				w.writeError(err)
+				w.writeError(w.join(entry.path, ignoreFile), err)
 				return
 			}
 		}
 	}
 	newIgnore = entry.ignore.Add(newIgnore)
+	if w.IsWatch && entry.prefix == "" && entry.fsys == nil {
+		w.addWatchDir(entry, newIgnore)
+	}
 
 	for _, f := range files {
-		if entry.info.Name() == ".git" {
-			w.checkEntry(&entryInfo{path: filepath.Join(entry.path, f.Name()), info: f})
-		} else {
-			w.checkEntry(&entryInfo{path: filepath.Join(entry.path, f.Name()), info: f, ignore: newIgnore, projectRoot: entry.projectRoot})
+		if w.skipHidden && isHidden(f.Name()) {
+			continue
+		}
+		child := w.child(entry, f)
+		if entry.info.Name() != ".git" {
+			child.ignore, child.projectRoot = newIgnore, entry.projectRoot
 		}
+		w.checkEntry(child)
 	}
 }
 
-func (w *Walker) writeError(err error) {
+// onRootDevice reports whether the directory is on the device of the root.
+// The device of the root is recorded when the root is checked,
+// and every directory is on it if the device of the root is unknown.
+func onRootDevice(entry *entryInfo) bool {
+	if entry.prefix != "" {
+		// members of an archive are on the device of the archive.
+		return true
+	}
+	id, ok := entryFileID(entry)
+	if entry.depth == 0 {
+		entry.rootDev, entry.rootDevOK = id.dev, ok
+	}
+	if !ok || !entry.rootDevOK {
+		return true
+	}
+	return id.dev == entry.rootDev
+}
+
+// entryFileID returns the file ID of the entry.
+// If the entry doesn't have it, like entries of the index, the file system is asked by Stat.
+func entryFileID(entry *entryInfo) (fileID, bool) {
+	if info, err := entry.info.Info(); err == nil {
+		if id, ok := fileIDOf(info); ok {
+			return id, true
+		}
+	}
+	if fsys, ok := entry.fsys.(fs.StatFS); ok {
+		if info, err := fsys.Stat(entry.name); err == nil {
+			return fileIDOf(info)
+		}
+	}
+	return fileID{}, false
+}
+
+func (*Walker) child(dir *entryInfo, f fs.DirEntry) *entryInfo {
+	child := &entryInfo{info: f, fsys: dir.fsys, prefix: dir.prefix, depth: dir.depth + 1, rootDev: dir.rootDev, rootDevOK: dir.rootDevOK}
+	switch {
+	case dir.prefix != "":
+		child.name = path.Join(dir.name, f.Name())
+		child.path = dir.prefix + child.name
+	case dir.fsys != nil:
+		child.path = path.Join(dir.path, f.Name())
+		child.name = child.path
+	default:
+		child.path = filepath.Join(dir.path, f.Name())
+		child.name = child.path
+	}
+	return child
+}
+
+func (w *Walker) writeError(path string, err error) {
 	if w.ignoreErr {
 		return
 	}
+	w.writingMutex.Lock()
 	w.IsErr = true
+	w.onError(&Error{Path: path, Err: err})
+	w.writingMutex.Unlock()
+}
+
+func (w *Walker) stop(err error) {
 	w.writingMutex.Lock()
-	if _, err := w.outerr.Write([]byte(err.Error() + "\n")); err != nil {
-		log.Printf("[ERROR] %v", err)
+	if w.stopErr == nil {
+		w.stopErr = err
 	}
 	w.writingMutex.Unlock()
+	w.cancel()
 }
 
-func (w *Walker) writeFile(path string, _ fs.DirEntry) {
+func (w *Walker) writeFile(entry *entryInfo) error {
+	var lines []filter.Line
+	if w.grepPrint {
+		lines = w.grepLines(entry)
+	}
+	var sumLine string
+	if w.checksum != nil {
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		var err error
+		if sumLine, err = checksumLine(entry, w.checksum); err != nil {
+			w.writeError(entry.path, err)
+			return nil
+		}
+	}
+
 	w.writingMutex.Lock()
-	switch w.printType {
-	case println:
-		if _, err := w.out.WriteString(path + "\n"); err != nil {
+	defer w.writingMutex.Unlock()
+	switch {
+	case sumLine != "":
+		if _, err := w.out.WriteString(sumLine); err != nil {
 			log.Printf("[ERROR] %v", err)
 		}
-	case print0:
-		if _, err := w.out.WriteString(path + "\x00"); err != nil {
+	case len(lines) > 0:
+		for _, line := range lines {
+			if _, err := fmt.Fprintf(w.out, "%s:%d:%s\n", entry.path, line.Num, line.Text); err != nil {
+				log.Printf("[ERROR] %v", err)
+			}
+		}
+	case len(entry.prints) > 0:
+		for _, terminator := range entry.prints {
+			if _, err := w.out.WriteString(entry.path + terminator); err != nil {
+				log.Printf("[ERROR] %v", err)
+			}
+		}
+	case w.printType == println:
+		if _, err := w.out.WriteString(entry.path + "\n"); err != nil {
+			log.Printf("[ERROR] %v", err)
+		}
+	case w.printType == print0:
+		if _, err := w.out.WriteString(entry.path + "\x00"); err != nil {
 			log.Printf("[ERROR] %v", err)
 		}
 	}
@@ -296,11 +761,60 @@ func (w *Walker) writeFile(path string, _ fs.DirEntry) {
 		}
 	default:
 	}
-	w.writingMutex.Unlock()
+	return nil
+}
+
+func (w *Walker) stat(path string) (fs.DirEntry, error) {
+	if w.fsys != nil {
+		info, err := fs.Stat(w.fsys, path)
+		if err != nil {
+			return nil, err
+		}
+		return fs.FileInfoToDirEntry(info), nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return newEntry(f)
+}
+
+func (w *Walker) join(dir, name string) string {
+	if w.fsys != nil {
+		return path.Join(dir, name)
+	}
+	return filepath.Join(dir, name)
 }
 
-func (w *Walker) readDir(dir string) (ds []fs.DirEntry, err error) {
-	f, err := os.Open(dir)
+func (w *Walker) readIgnore(dir *entryInfo, name string) (*filter.Gitignore, error) {
+	if dir.fsys != nil {
+		return filter.NewGitIgnoreFS(dir.fsys, dir.name, path.Join(dir.name, name))
+	}
+	return w.newGitIgnore(filepath.Join(dir.projectRoot, dir.path), filepath.Join(dir.path, name))
+}
+
+func (w *Walker) newGitIgnore(root, path string) (*filter.Gitignore, error) {
+	if w.cache != nil {
+		return w.cache.gitignore(root, path)
+	}
+	return filter.NewGitIgnore(root, path)
+}
+
+func (w *Walker) readDir(dir *entryInfo) (ds []fs.DirEntry, err error) {
+	if dir.listed {
+		// read by the matcher
+		ds, err = dir.listing, dir.listErr
+		dir.listing, dir.listErr, dir.listed = nil, nil, false
+		return ds, err
+	}
+	if dir.fsys != nil {
+		return fs.ReadDir(dir.fsys, dir.name)
+	}
+	if w.cache != nil {
+		return w.cache.readDir(dir.name)
+	}
+	f, err := os.Open(dir.name)
 	if err != nil {
 		return nil, err
 	}
@@ -318,6 +832,70 @@ func (*Walker) getIgnore(files []fs.DirEntry) string {
 	return ""
 }
 
+// grepLines returns lines matched by the content expressions in the order of line numbers.
+func (w *Walker) grepLines(entry *entryInfo) []filter.Line {
+	var lines []filter.Line
+	for _, c := range w.contents {
+		ls, err := c.Lines(entry.path, entry)
+		if err != nil {
+			w.writeError(entry.path, err)
+			return nil
+		}
+		lines = append(lines, ls...)
+	}
+	slices.SortFunc(lines, func(a, b filter.Line) int { return a.Num - b.Num })
+	return slices.CompactFunc(lines, func(a, b filter.Line) bool { return a.Num == b.Num })
+}
+
+func (e *entryInfo) entry() Entry {
+	return Entry{Path: e.path, DirEntry: e}
+}
+
+func (e *entryInfo) Name() string { return e.info.Name() }
+
+func (e *entryInfo) IsDir() bool { return e.info.IsDir() }
+
+func (e *entryInfo) Type() fs.FileMode { return e.info.Type() }
+
+func (e *entryInfo) Info() (fs.FileInfo, error) { return e.info.Info() }
+
+// ReadDir reads the directory for -empty. The entries are reused by the walk.
+func (e *entryInfo) ReadDir() ([]fs.DirEntry, error) {
+	if !e.listed {
+		e.listing, e.listErr = e.walker.readDir(e)
+		e.listed = true
+	}
+	return e.listing, e.listErr
+}
+
+// Depth returns the depth from the root for -where.
+func (e *entryInfo) Depth() int { return e.depth }
+
+func (e *entryInfo) Prune() { e.pruned = true }
+
+func (e *entryInfo) Print(terminator string) { e.prints = append(e.prints, terminator) }
+
+// Open opens the content of the entry, including a member of an archive.
+func (e *entryInfo) Open() (io.ReadCloser, error) {
+	if e.fsys == nil {
+		return os.Open(e.name)
+	}
+	return e.fsys.Open(e.name)
+}
+
+// Error returns the message of Err, which usually contains the path.
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func isHidden(name string) bool {
+	return len(name) > 1 && name[0] == '.'
+}
+
 func (d entryInfos) String() string {
 	paths := make([]string, 0, len(d))
 	for _, p := range d {
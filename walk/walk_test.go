@@ -1,19 +1,32 @@
 package walk
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
 	"testing"
 	"testing/fstest"
+
+	"github.com/komem3/fing/filter"
 )
 
 var tmpDir = os.TempDir()
 
 var testFs = fstest.MapFS{
-	"testdata/.gitignore":         {},
+	"testdata/.gitignore":         {Data: []byte("*.png\n")},
 	"testdata/sample.txt":         {},
 	"testdata/example.png":        {},
-	"testdata/jpg_dir/.gitignore": {},
+	"testdata/.hidden/hidden.txt": {},
+	"testdata/jpg_dir/.gitignore": {Data: []byte("*.jpg\n!sample.jpg\n")},
 	"testdata/jpg_dir/sample.jpg": {},
+	"testdata/jpg_dir/other.jpg":  {},
 	"testdata/link/sample.ln":     {},
 }
 
@@ -41,3 +54,225 @@ func TestWalker_getIgnore(t *testing.T) {
 		})
 	}
 }
+
+func TestWalker_Walk(t *testing.T) {
+	for _, tt := range []struct {
+		name  string
+		opts  func(*Options)
+		paths []string
+	}{
+		{
+			"all entries",
+			func(*Options) {},
+			[]string{
+				"testdata", "testdata/.gitignore", "testdata/sample.txt", "testdata/example.png",
+				"testdata/.hidden", "testdata/.hidden/hidden.txt",
+				"testdata/jpg_dir", "testdata/jpg_dir/.gitignore", "testdata/jpg_dir/sample.jpg", "testdata/jpg_dir/other.jpg",
+				"testdata/link", "testdata/link/sample.ln",
+			},
+		},
+		{
+			"matcher and prune",
+			func(opts *Options) {
+				opts.Matcher = mustExp(filter.NewBuilder().Name("*.txt").Or().Name("*.jpg").Build())
+				opts.Prune = mustExp(filter.NewFileName(".hidden"))
+			},
+			[]string{"testdata/sample.txt", "testdata/jpg_dir/sample.jpg", "testdata/jpg_dir/other.jpg"},
+		},
+		{
+			"gitignore",
+			func(opts *Options) {
+				opts.Matcher = mustExp(filter.NewFileType("f"))
+				opts.Gitignore = true
+			},
+			[]string{
+				"testdata/.gitignore", "testdata/sample.txt", "testdata/.hidden/hidden.txt",
+				"testdata/jpg_dir/.gitignore", "testdata/jpg_dir/sample.jpg", "testdata/link/sample.ln",
+			},
+		},
+		{
+			"skip hidden",
+			func(opts *Options) {
+				opts.SkipHidden = true
+				opts.MaxDepth = 1
+			},
+			[]string{"testdata", "testdata/sample.txt", "testdata/example.png", "testdata/jpg_dir", "testdata/link"},
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			opts := DefaultOptions()
+			opts.FS = testFs
+			tt.opts(&opts)
+
+			var paths []string
+			err := NewWalker(opts).Walk(context.Background(), []string{"testdata"}, func(e Entry) error {
+				paths = append(paths, e.Path)
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			sort.Strings(paths)
+			sort.Strings(tt.paths)
+			if !reflect.DeepEqual(tt.paths, paths) {
+				t.Errorf("Walk mismatch\nwant: %s\ngot: %s", tt.paths, paths)
+			}
+		})
+	}
+}
+
+func TestWalker_Walk_stop(t *testing.T) {
+	fsys := make(fstest.MapFS)
+	for i := 0; i < 50; i++ {
+		for j := 0; j < 200; j++ {
+			fsys[fmt.Sprintf("root/%d/%d.txt", i, j)] = &fstest.MapFile{}
+		}
+	}
+	stop := errors.New("stop")
+	var count, after int
+	err := NewWalker(Options{FS: fsys, MaxDepth: NoLimit}).Walk(context.Background(), []string{"root"}, func(e Entry) error {
+		count++
+		if count > 100 {
+			after++
+		}
+		if count == 100 {
+			return stop
+		}
+		return nil
+	})
+	if !errors.Is(err, stop) {
+		t.Errorf("Walk want %v, but got %v", stop, err)
+	}
+	if after != 0 {
+		t.Errorf("callback is called %d times after stop", after)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := NewWalker(Options{FS: testFs, MaxDepth: NoLimit}).Walk(ctx, []string{"testdata"}, func(Entry) error {
+		t.Error("callback is called after cancel")
+		return nil
+	}); !errors.Is(err, context.Canceled) {
+		t.Errorf("Walk want %v, but got %v", context.Canceled, err)
+	}
+}
+
+func TestWalker_All(t *testing.T) {
+	opts := DefaultOptions()
+	opts.FS = testFs
+	opts.MaxDepth = 1
+	var count int
+	for entry, err := range NewWalker(opts).All(context.Background(), []string{"testdata/missing", "testdata"}) {
+		if err != nil {
+			var walkErr *Error
+			if !errors.As(err, &walkErr) || walkErr.Path != "testdata/missing" {
+				t.Errorf("unexpected error: %v", err)
+			}
+			continue
+		}
+		if entry.Path == "" || entry.DirEntry == nil {
+			t.Errorf("invalid entry %+v", entry)
+		}
+		count++
+		if count == 2 {
+			break
+		}
+	}
+	if count != 2 {
+		t.Errorf("All yields %d entries, want 2", count)
+	}
+}
+
+// writeTree creates files of slash-separated names under dir, whose contents are the names.
+// A name ending with a slash is created as an empty directory.
+func writeTree(t *testing.T, dir string, names ...string) {
+	t.Helper()
+	for _, name := range names {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if strings.HasSuffix(name, "/") {
+			if err := os.MkdirAll(path, 0o755); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(name), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func mustExp[T filter.FileExp](exp T, err error) filter.FileExp {
+	if err != nil {
+		panic(err)
+	}
+	return exp
+}
+
+func TestWalker_Run_empty(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, "a/b/c.txt", "a/empty.txt", "d/.keep", "e/")
+	if err := os.WriteFile(filepath.Join(dir, "a", "empty.txt"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	out := new(bytes.Buffer)
+	walker, roots, err := NewWalkerFromArgs([]string{"fing", dir, "-empty", "-o", "-name", "c.txt"}, out, out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	walker.Run(roots)
+
+	// directories read by -empty are still scanned.
+	got := strings.Split(strings.TrimSpace(out.String()), "\n")
+	sort.Strings(got)
+	want := []string{
+		filepath.Join(dir, "a", "b", "c.txt"),
+		filepath.Join(dir, "a", "empty.txt"),
+		filepath.Join(dir, "e"),
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("output mismatch\nwant: %v\ngot: %v", want, got)
+	}
+}
+
+func TestNewWalkerFromArgs_outputOptions(t *testing.T) {
+	for _, args := range [][]string{
+		{"-snapshot", "s", "-checksum", "sha256"},
+		{"-checksum", "sha256", "-duplicates"},
+		{"-index", "build", "-duplicates"},
+		{"-duplicates", "-fuzzy", "x"},
+		{"-snapshot", "s", "-fuzzy", "x"},
+	} {
+		_, _, err := newWalkerFromArgs(append([]string{"fing", "."}, args...), io.Discard, io.Discard, false)
+		if err == nil || !strings.Contains(err.Error(), "can't be used with") {
+			t.Errorf("%v: want an error of conflicting options, but got %v", args, err)
+		}
+	}
+}
+
+func TestNewWalker_Run(t *testing.T) {
+	stdout := os.Stdout
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+	opts := DefaultOptions()
+	opts.FS = testFs
+	opts.MaxDepth = 0
+	NewWalker(opts).Run([]string{"testdata"})
+	w.Close()
+	out, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != "testdata\n" {
+		t.Errorf("Run output want testdata, but got %q", out)
+	}
+}
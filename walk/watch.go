@@ -0,0 +1,131 @@
+package walk
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/komem3/fing/filter"
+)
+
+// WatchEvent is an event of -watch printed with -json.
+type WatchEvent struct {
+	// Event is create, modify or move.
+	Event string `json:"event"`
+	Path  string `json:"path"`
+}
+
+const (
+	createEvent = "create"
+	modifyEvent = "modify"
+	moveEvent   = "move"
+)
+
+// watchDir is a directory watched by -watch.
+type watchDir struct {
+	entry *entryInfo
+	// ignore is the ignore of children.
+	ignore *filter.Gitignore
+}
+
+// addWatchDir records the directory scanned by scanDir. It is watched by Watch.
+func (w *Walker) addWatchDir(entry *entryInfo, ignore *filter.Gitignore) {
+	dir := &watchDir{entry: entry, ignore: ignore}
+	if entry.info.Name() == ".git" {
+		dir.ignore = nil
+	}
+	w.dirMutex.Lock()
+	w.watchDirs = append(w.watchDirs, dir)
+	w.dirMutex.Unlock()
+}
+
+// takeWatchDirs returns directories which are not watched yet.
+func (w *Walker) takeWatchDirs() []*watchDir {
+	w.dirMutex.Lock()
+	defer w.dirMutex.Unlock()
+	dirs := w.watchDirs
+	w.watchDirs = nil
+	return dirs
+}
+
+// handleEvent checks the entry of name in dir in the same way as the walk, and prints it if it matches.
+// Created directories are scanned, and their directories are watched after this.
+func (w *Walker) handleEvent(dir *watchDir, name, event string) {
+	if w.skipHidden && isHidden(name) {
+		return
+	}
+	if w.depth >= 0 && dir.entry.depth+1 > w.depth {
+		return
+	}
+	path := filepath.Join(dir.entry.path, name)
+	info, err := os.Lstat(path)
+	if err != nil {
+		// the file has been removed already.
+		if !os.IsNotExist(err) {
+			w.writeError(path, err)
+		}
+		return
+	}
+	entry := &entryInfo{
+		path:        path,
+		name:        path,
+		info:        fs.FileInfoToDirEntry(info),
+		ignore:      dir.ignore,
+		projectRoot: dir.entry.projectRoot,
+		depth:       dir.entry.depth + 1,
+		rootDev:     dir.entry.rootDev,
+		rootDevOK:   dir.entry.rootDevOK,
+	}
+
+	w.onMatch = func(e *entryInfo) error {
+		if e == entry {
+			w.writeEvent(event, e)
+		} else {
+			w.writeEvent(createEvent, e)
+		}
+		return nil
+	}
+	w.directories = w.directories[:0]
+	w.checkEntry(entry)
+	if event == modifyEvent {
+		return
+	}
+	for len(w.directories) > 0 {
+		d := w.directories[len(w.directories)-1]
+		w.directories = w.directories[:len(w.directories)-1]
+		if w.depth >= 0 && d.depth >= w.depth {
+			continue
+		}
+		w.scanDir(d)
+	}
+}
+
+func (w *Walker) writeEvent(event string, entry *entryInfo) {
+	if !w.json {
+		if err := w.writeFile(entry); err != nil {
+			log.Printf("[ERROR] %v", err)
+		}
+		return
+	}
+	b, err := json.Marshal(WatchEvent{Event: event, Path: entry.path})
+	if err != nil {
+		log.Printf("[ERROR] %v", err)
+		return
+	}
+	w.writingMutex.Lock()
+	defer w.writingMutex.Unlock()
+	if _, err := fmt.Fprintf(w.out, "%s\n", b); err != nil {
+		log.Printf("[ERROR] %v", err)
+	}
+}
+
+func (w *Walker) flush() {
+	w.writingMutex.Lock()
+	defer w.writingMutex.Unlock()
+	if err := w.out.Flush(); err != nil {
+		log.Printf("[ERROR] %v", err)
+	}
+}
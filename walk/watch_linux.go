@@ -0,0 +1,148 @@
+package walk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+const watchMask = syscall.IN_CREATE | syscall.IN_CLOSE_WRITE | syscall.IN_ATTRIB | syscall.IN_MOVED_TO | syscall.IN_ONLYDIR
+
+// Watch watches directories scanned by Run with inotify until ctx is done,
+// and prints entries which are created, modified or moved into a matching state.
+func (w *Walker) Watch(ctx context.Context) error {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		if errors.Is(err, syscall.EMFILE) {
+			return fmt.Errorf("inotify instance limit is reached. Increase fs.inotify.max_user_instances: %w", err)
+		}
+		return fmt.Errorf("inotify_init: %w", err)
+	}
+	// a non-blocking file is registered to the runtime poller, so the deadline unblocks Read.
+	file := os.NewFile(uintptr(fd), "inotify")
+	defer file.Close()
+	go func() {
+		<-ctx.Done()
+		_ = file.SetReadDeadline(time.Now())
+	}()
+
+	w.ctx = ctx
+	w.setCommandContext(ctx)
+	w.flushTick = time.NewTicker(time.Hour)
+	defer w.flushTick.Stop()
+	dirs := make(map[int]*watchDir)
+	addWatches := func() error {
+		for _, dir := range w.takeWatchDirs() {
+			wd, err := syscall.InotifyAddWatch(fd, dir.entry.path, watchMask)
+			if err != nil {
+				if errors.Is(err, syscall.ENOSPC) {
+					return fmt.Errorf("inotify watch limit is reached while watching %d directories. "+
+						"Increase fs.inotify.max_user_watches (current %s), such as 'sysctl fs.inotify.max_user_watches=524288'",
+						len(dirs), maxUserWatches())
+				}
+				w.writeError(dir.entry.path, err)
+				continue
+			}
+			dirs[wd] = dir
+		}
+		return nil
+	}
+	if err := addWatches(); err != nil {
+		return err
+	}
+
+	type eventKey struct {
+		wd   int32
+		name string
+	}
+	// created are new regular files, which are printed when they are closed after writing.
+	created := make(map[eventKey]struct{})
+	buf := make([]byte, 64*(syscall.SizeofInotifyEvent+syscall.NAME_MAX+1))
+	for {
+		n, err := file.Read(buf)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("read inotify events: %w", err)
+		}
+		for offset := 0; offset+syscall.SizeofInotifyEvent <= n; {
+			event := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+			nameBytes := buf[offset+syscall.SizeofInotifyEvent : offset+syscall.SizeofInotifyEvent+int(event.Len)]
+			offset += syscall.SizeofInotifyEvent + int(event.Len)
+
+			if event.Mask&syscall.IN_Q_OVERFLOW != 0 {
+				w.writingMutex.Lock()
+				w.printError(errors.New("inotify event queue overflowed, so some changes are not printed. " +
+					"Increase fs.inotify.max_queued_events"))
+				w.writingMutex.Unlock()
+				continue
+			}
+			dir, ok := dirs[int(event.Wd)]
+			if !ok {
+				continue
+			}
+			if event.Mask&syscall.IN_IGNORED != 0 {
+				delete(dirs, int(event.Wd))
+				continue
+			}
+			name := strings.TrimRight(string(nameBytes), "\x00")
+			if name == "" {
+				continue
+			}
+			key := eventKey{event.Wd, name}
+			switch {
+			case event.Mask&syscall.IN_CREATE != 0:
+				if isWrittenFile(filepath.Join(dir.entry.path, name)) {
+					created[key] = struct{}{}
+					continue
+				}
+				w.handleEvent(dir, name, createEvent)
+			case event.Mask&syscall.IN_CLOSE_WRITE != 0:
+				if _, ok := created[key]; ok {
+					delete(created, key)
+					w.handleEvent(dir, name, createEvent)
+					continue
+				}
+				w.handleEvent(dir, name, modifyEvent)
+			case event.Mask&syscall.IN_MOVED_TO != 0:
+				delete(created, key)
+				w.handleEvent(dir, name, moveEvent)
+			default:
+				if _, ok := created[key]; ok {
+					// the created file is printed when it is closed.
+					continue
+				}
+				w.handleEvent(dir, name, modifyEvent)
+			}
+		}
+		if err := addWatches(); err != nil {
+			return err
+		}
+		w.flush()
+	}
+}
+
+// isWrittenFile reports whether the created file is a new regular file, which will be closed after writing.
+// Hard links are not written, so they are printed when they are created.
+func isWrittenFile(path string) bool {
+	var st syscall.Stat_t
+	if err := syscall.Lstat(path, &st); err != nil {
+		return false
+	}
+	return st.Mode&syscall.S_IFMT == syscall.S_IFREG && st.Nlink == 1
+}
+
+func maxUserWatches() string {
+	b, err := os.ReadFile("/proc/sys/fs/inotify/max_user_watches")
+	if err != nil {
+		return "unknown"
+	}
+	return strings.TrimSpace(string(b))
+}
@@ -0,0 +1,100 @@
+package walk
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWalker_Watch(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "node_modules"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	out := new(syncBuffer)
+	walker, roots, err := NewWalkerFromArgs([]string{
+		"fing", dir, "-name", "node_modules", "-prune", "-o", "-name", "*.go", "-watch", "-json",
+	}, out, out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	walker.Run(roots)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- walker.Watch(ctx) }()
+	defer cancel()
+	// waitFor waits until the output contains the path, writing the file each time if write is set.
+	waitFor := func(name string, write bool) {
+		t.Helper()
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		for deadline := time.Now().Add(5 * time.Second); !strings.Contains(out.String(), `"`+path+`"`); {
+			if time.Now().After(deadline) {
+				t.Fatalf("%s is not printed:\n%s", name, out.String())
+			}
+			if write {
+				writeTree(t, dir, name)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	// the watch is ready when a written file is printed.
+	waitFor("ready.go", true)
+
+	writeTree(t, dir, "a.go", "b.txt", "node_modules/c.go", "sub/d.go")
+	waitFor("sub/d.go", false)
+	writeTree(t, dir, "sub/e.go")
+	if err := os.Rename(filepath.Join(dir, "b.txt"), filepath.Join(dir, "f.go")); err != nil {
+		t.Fatal(err)
+	}
+	waitFor("sub/e.go", false)
+	waitFor("f.go", false)
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	got := out.String()
+	for _, want := range []string{
+		`{"event":"create","path":"` + filepath.Join(dir, "a.go") + `"}`,
+		`{"event":"create","path":"` + filepath.Join(dir, "sub", "d.go") + `"}`,
+		`{"event":"create","path":"` + filepath.Join(dir, "sub", "e.go") + `"}`,
+		`{"event":"move","path":"` + filepath.Join(dir, "f.go") + `"}`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output want %s, but got:\n%s", want, got)
+		}
+	}
+	// a file which is created and written is printed once.
+	if n := strings.Count(got, filepath.Join(dir, "a.go")); n != 1 {
+		t.Errorf("a.go is printed %d times:\n%s", n, got)
+	}
+	for _, unwanted := range []string{"b.txt", "c.go"} {
+		if strings.Contains(got, unwanted) {
+			t.Errorf("output must not contain %s:\n%s", unwanted, got)
+		}
+	}
+}
+
+// syncBuffer is a bytes.Buffer which is read while the watcher writes.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
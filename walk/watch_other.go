@@ -0,0 +1,13 @@
+//go:build !linux
+
+package walk
+
+import (
+	"context"
+	"errors"
+)
+
+// Watch is supported only on Linux.
+func (w *Walker) Watch(context.Context) error {
+	return errors.New("-watch is supported only on Linux")
+}